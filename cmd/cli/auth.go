@@ -1,28 +1,24 @@
 package main
 
 import (
-	"fmt"
 	"log"
-	"time"
 
 	"github.com/fatih/color"
 )
 
 func doAuth() error {
 	//migrations
-	dbType := core.DB.DataType
-	fileName := fmt.Sprintf("%d_create_auth_tables", time.Now().UnixMicro())
-	upFile := core.RootPath + "/migrations/" + fileName + ".up.sql"
-	downFile := core.RootPath + "/migrations/" + fileName + ".down.sql"
-
-	log.Println(dbType, upFile, downFile)
-
-	if err := copyFilefromTemplate("templates/migrations/"+dbType+"/auth_tables.sql", upFile); err != nil {
+	up, err := migrationTemplate("auth_tables.sql")
+	if err != nil {
 		exitGracefully(err)
 	}
-	if err := copyDataToFile([]byte("drop table if exists users cascade; drop table if exists tokens cascade; drop table if exists remember_tokens;"), downFile); err != nil {
+	down := "drop table if exists tokens cascade; drop table if exists remember_tokens cascade; drop table if exists users cascade;"
+
+	fileName, err := createMigration("create_auth_tables", up, down)
+	if err != nil {
 		exitGracefully(err)
 	}
+	log.Println(core.DB.DataType, fileName)
 
 	//run migrations
 	if err := doMigrate("up", ""); err != nil {
@@ -37,7 +33,7 @@ func doAuth() error {
 	if err := copyFilefromTemplate("templates/data/token.go.txt", core.RootPath+"/src/data/token.go"); err != nil {
 		exitGracefully(err)
 	}
-	if err := copyFilefromTemplate("templates/data/remember_token.go.txt", core.RootPath+"/src/data/token.go"); err != nil {
+	if err := copyFilefromTemplate("templates/data/remember_token.go.txt", core.RootPath+"/src/data/remember_token.go"); err != nil {
 		exitGracefully(err)
 	}
 
@@ -49,7 +45,7 @@ func doAuth() error {
 	if err := copyFilefromTemplate("templates/middleware/auth-token.go.txt", core.RootPath+"/src/middleware/auth-token.go"); err != nil {
 		exitGracefully(err)
 	}
-	if err := copyFilefromTemplate("templates/middleware/remember.go.txt", core.RootPath+"/src/middleware/auth-token.go"); err != nil {
+	if err := copyFilefromTemplate("templates/middleware/remember.go.txt", core.RootPath+"/src/middleware/remember.go"); err != nil {
 		exitGracefully(err)
 	}
 	//copy handler
@@ -76,12 +72,25 @@ func doAuth() error {
 		exitGracefully(err)
 	}
 
+	//copy seeder
+	if err := core.CreateDirIfNotExists(core.RootPath + "/seeds"); err != nil {
+		exitGracefully(err)
+	}
+	if err := copySeedsMain(); err != nil {
+		exitGracefully(err)
+	}
+	if err := copyFilefromTemplate("templates/seeds/admin_user.go.txt", core.RootPath+"/seeds/admin_user.go"); err != nil {
+		exitGracefully(err)
+	}
+
 	color.Yellow(" - users, tokens, and remeber_tokens migrations created and executed")
 	color.Yellow(" - user and token models created")
 	color.Yellow(" - auth middleware created")
+	color.Yellow(" - admin user seeder created; run it with: medego db seed admin_user")
 	color.Yellow("")
 	color.Yellow("Don't forget to add user and token models in data/models.go")
 	color.Yellow("And to add appropriate middleware to your routes!")
+	color.Yellow("To accept tokens on websocket connections: app.WebSocket.Token = models.Tokens.UserIDForToken")
 
 	return nil
 }
@@ -68,11 +68,18 @@ func showHelp() {
 	migrate down		- reverse the most recent migration
 	migrate reset		- runs all down migrations in reverse order, and then al up migrations
 	make migration <name>	- creates up and down migrations in the migrations directory 
+				  --create=<table> scaffolds a new table, --table=<table> alters one
+				  --from-models generates it from the differences between src/data and the database
 	make auth		- creates and runs migrations for authentification tables, and creates models an middleware 
 	make handler <name>	- creates stub handler in the handlers directory 
 	make model <name>	- creates new model in the data directory  
 	make session		- create a table in database as a session store
+	make mailqueue		- create a table in database as a mail queue store (MAIL_QUEUE=database)
 	make mail <name>	- creates two starter mail templates in the mail directory
+				  --markdown creates one Markdown template instead
+	make seeder <name>	- creates a Go seeder in the seeds directory (--sql for a SQL seed)
+	db seed [name]		- runs all seeders, or only the named one
+	db seed --fresh		- rolls back all migrations, runs them again, then runs the seeders
 	`)
 }
 func checkError(err error, message string) {
@@ -0,0 +1,17 @@
+package main
+
+func doMailQueueTable() error {
+	up, err := migrationTemplate("mail_queue.sql")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	if _, err := createMigration("create_mail_queue_table", up, "drop table mail_queue"); err != nil {
+		exitGracefully(err)
+	}
+
+	if err := doMigrate("up", ""); err != nil {
+		exitGracefully(err)
+	}
+	return nil
+}
@@ -3,15 +3,20 @@ package main
 import (
 	"errors"
 	"os"
+	"strings"
 
 	"github.com/PrinMeshia/medego"
 	"github.com/fatih/color"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 const version = "1.0.0"
 
 var core medego.Medego
 
+// flags holds the --name and --name=value options given on the command line
+var flags = make(map[string]string)
+
 func main() {
 	var message string
 	arg1, arg2, arg3, err := validateInput()
@@ -49,6 +54,15 @@ func main() {
 			exitGracefully(err)
 		}
 
+	case "db":
+		if arg2 != "seed" {
+			exitGracefully(errors.New("db requires a subcommand: (seed)"))
+		}
+		if err = doSeed(arg3, hasFlag("fresh")); err != nil {
+			exitGracefully(err)
+		}
+		message = "Database seeded successfully"
+
 	default:
 		showHelp()
 	}
@@ -62,16 +76,38 @@ func validateInput() (string, string, string, error) {
 		return "", "", "", errors.New("command required")
 	}
 
-	arg1 := os.Args[1]
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(arg, "--") {
+			name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+			flags[name] = value
+			continue
+		}
+		args = append(args, arg)
+	}
+
+	if len(args) == 0 {
+		color.Red("Error: command required")
+		showHelp()
+		return "", "", "", errors.New("command required")
+	}
+
+	arg1 := args[0]
 	arg2, arg3 := "", ""
 
-	if len(os.Args) >= 3 {
-		arg2 = os.Args[2]
+	if len(args) >= 2 {
+		arg2 = args[1]
 	}
 
-	if len(os.Args) >= 4 {
-		arg3 = os.Args[3]
+	if len(args) >= 3 {
+		arg3 = args[2]
 	}
 
 	return arg1, arg2, arg3, nil
 }
+
+// hasFlag reports whether --name was given on the command line
+func hasFlag(name string) bool {
+	_, ok := flags[name]
+	return ok
+}
@@ -2,10 +2,8 @@ package main
 
 import (
 	"errors"
-	"fmt"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/fatih/color"
 	"github.com/gertd/go-pluralize"
@@ -19,21 +17,7 @@ func doMake(arg2, arg3 string) error {
 		rnd := core.RandomString(32)
 		color.Yellow("32 characters encryption key: %s", rnd)
 	case "migration":
-		dbType := core.DB.DataType
-		if arg3 == "" {
-			exitGracefully(errors.New("you must give the migration a name"))
-		}
-
-		fileName := fmt.Sprintf("%d_%s", time.Now().UnixMicro(), arg3)
-
-		upFile := core.RootPath + "/migrations/" + fileName + "." + dbType + ".up.sql"
-		downFile := core.RootPath + "/migrations/" + fileName + "." + dbType + ".down.sql"
-
-		if err := copyFilefromTemplate("templates/migrations/"+dbType+"/migration.up.sql", upFile); err != nil {
-			exitGracefully(err)
-		}
-
-		if err := copyFilefromTemplate("templates/migrations/"+dbType+"/migration.down.sql", downFile); err != nil {
+		if err := doMakeMigration(arg3); err != nil {
 			exitGracefully(err)
 		}
 	case "auth":
@@ -97,6 +81,14 @@ func doMake(arg2, arg3 string) error {
 		if arg3 == "" {
 			exitGracefully(errors.New("template name required"))
 		}
+		if hasFlag("markdown") {
+			markdownMail := core.RootPath + "/mail/" + strings.ToLower(arg3) + ".md.tmpl"
+			if err := copyFilefromTemplate("templates/mailer/mail.md.tmpl", markdownMail); err != nil {
+				exitGracefully(err)
+			}
+			break
+		}
+
 		htmlMail := core.RootPath + "/mail/" + strings.ToLower(arg3) + ".html.tmpl"
 		plainMail := core.RootPath + "/mail/" + strings.ToLower(arg3) + ".plain.tmpl"
 
@@ -112,6 +104,16 @@ func doMake(arg2, arg3 string) error {
 			exitGracefully(err)
 		}
 
+	case "mailqueue":
+		if err := doMailQueueTable(); err != nil {
+			exitGracefully(err)
+		}
+
+	case "seeder":
+		if err := doMakeSeeder(arg3); err != nil {
+			exitGracefully(err)
+		}
+
 	}
 
 	return nil
@@ -1,5 +1,18 @@
 package main
 
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PrinMeshia/medego/schema"
+	"github.com/fatih/color"
+	"github.com/iancoleman/strcase"
+)
+
 func doMigrate(arg2, arg3 string) error {
 	dsn := getDSN()
 
@@ -31,3 +44,152 @@ func doMigrate(arg2, arg3 string) error {
 	}
 	return nil
 }
+
+// doMakeMigration creates an empty migration, or one scaffolding a table
+// when --create=<table> or --table=<table> is given
+func doMakeMigration(name string) error {
+	if name == "" {
+		return errors.New("you must give the migration a name")
+	}
+
+	if hasFlag("from-models") {
+		return doMigrationFromModels(name)
+	}
+
+	templateName, table := "migration", ""
+	switch {
+	case hasFlag("create"):
+		templateName, table = "create_table", flags["create"]
+	case hasFlag("table"):
+		templateName, table = "alter_table", flags["table"]
+	}
+
+	if templateName != "migration" && table == "" {
+		return errors.New("a table name is required, e.g. --create=users or --table=users")
+	}
+
+	up, err := migrationTemplate(templateName + ".up.sql")
+	if err != nil {
+		return err
+	}
+
+	down, err := migrationTemplate(templateName + ".down.sql")
+	if err != nil {
+		return err
+	}
+
+	up = strings.ReplaceAll(up, "$TABLENAME$", table)
+	down = strings.ReplaceAll(down, "$TABLENAME$", table)
+
+	_, err = createMigration(name, up, down)
+	return err
+}
+
+// doMigrationFromModels compares the models in src/data with the database schema and
+// writes a migration creating the missing tables, columns and indexes
+func doMigrationFromModels(name string) error {
+	dialect, err := schema.ParseDialect(core.DB.DataType)
+	if err != nil {
+		return err
+	}
+
+	models, err := schema.ParseModels(core.RootPath + "/src/data")
+	if err != nil {
+		return err
+	}
+
+	db, err := core.OpenDB(core.DB.DataType, core.BuildDSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	core.DB.Pool = db
+
+	live, err := schema.Inspect(core.DB.Pool, dialect)
+	if err != nil {
+		return err
+	}
+
+	m := schema.Diff(models, live, dialect)
+	if m.Empty() {
+		color.Yellow("Models and database schema are in sync, no migration created")
+		return nil
+	}
+
+	fileName, err := createMigration(name, m.UpSQL(), m.DownSQL())
+	if err != nil {
+		return err
+	}
+
+	color.Yellow(" - migration %s created from %d models", fileName, len(models))
+	return nil
+}
+
+// migrationDBType returns the name of the migration templates folder for the database type
+func migrationDBType() (string, error) {
+	switch core.DB.DataType {
+	case "mysql", "mariadb":
+		return "mysql", nil
+	case "postgres", "postgresql", "pgx":
+		return "postgres", nil
+	case "sqlite", "sqlite3":
+		return "", errors.New("there are no migration templates for sqlite, write the migration by hand or use --from-models")
+	default:
+		return "", fmt.Errorf("there are no migration templates for database type %q", core.DB.DataType)
+	}
+}
+
+// migrationTemplate reads a migration template for the current database type
+func migrationTemplate(name string) (string, error) {
+	dbType, err := migrationDBType()
+	if err != nil {
+		return "", err
+	}
+	data, err := templateFS.ReadFile("templates/migrations/" + dbType + "/" + name)
+	if err != nil {
+		return "", fmt.Errorf("no %s migration template for database type %q", name, dbType)
+	}
+	return string(data), nil
+}
+
+// createMigration writes the up and down files of a new migration and returns their common base name,
+// <version>_<name>, as expected by golang-migrate
+func createMigration(name, up, down string) (string, error) {
+	fileName := fmt.Sprintf("%d_%s", nextMigrationVersion(), strcase.ToSnake(name))
+	upFile := core.RootPath + "/migrations/" + fileName + ".up.sql"
+	downFile := core.RootPath + "/migrations/" + fileName + ".down.sql"
+
+	if fileExists(upFile) || fileExists(downFile) {
+		return "", errors.New(fileName + " already exists!")
+	}
+
+	if err := copyDataToFile([]byte(up), upFile); err != nil {
+		return "", err
+	}
+	if err := copyDataToFile([]byte(down), downFile); err != nil {
+		return "", err
+	}
+	return fileName, nil
+}
+
+// nextMigrationVersion returns a timestamp based version, always greater than the
+// versions of the existing migrations so that new files sort after them
+func nextMigrationVersion() int64 {
+	version := time.Now().UnixMicro()
+
+	files, err := os.ReadDir(core.RootPath + "/migrations")
+	if err != nil {
+		return version
+	}
+
+	for _, file := range files {
+		prefix, _, found := strings.Cut(file.Name(), "_")
+		if !found {
+			continue
+		}
+		if v, err := strconv.ParseInt(prefix, 10, 64); err == nil && v >= version {
+			version = v + 1
+		}
+	}
+	return version
+}
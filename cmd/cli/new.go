@@ -21,6 +21,7 @@ const (
 var templateFiles = map[string]string{
 	"env":    "templates/env.txt",
 	"go.mod": "templates/go.mod.txt",
+	"lang":   "templates/lang/en.json",
 }
 
 var appURL string
@@ -73,6 +74,14 @@ func doNew(appName string) {
 
 	checkError(copyDataToFile([]byte(env), filepath.Join(".", appName, ".env")), "Creating .env file")
 
+	//create the default message catalog, with the validation messages, unless the skeleton has one
+	langFile := filepath.Join(".", appName, "lang", "en.json")
+	if !fileExists(langFile) {
+		color.Yellow("\tCreating lang/en.json file...")
+		checkError(os.MkdirAll(filepath.Dir(langFile), 0755), "Creating lang directory")
+		checkError(copyFilefromTemplate(templateFiles["lang"], langFile), "Creating lang/en.json file")
+	}
+
 	//Makefile
 	if runtime.GOOS == "windows" {
 		copyMakefile(appName, fmt.Sprintf("./%s/MakefileWin", appName))
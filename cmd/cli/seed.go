@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/iancoleman/strcase"
+)
+
+func doSeed(name string, fresh bool) error {
+	if fresh {
+		dsn := getDSN()
+		if err := core.MigrateDownAll(dsn); err != nil {
+			return err
+		}
+		if err := core.MigrateUp(dsn); err != nil {
+			return err
+		}
+		color.Yellow(" - migrations rolled back and executed")
+	}
+
+	// go seeders need the application code, so they are run by the seeds program
+	if fileExists(core.RootPath + "/seeds/main.go") {
+		args := []string{"run", "./seeds"}
+		if name != "" {
+			args = append(args, name)
+		}
+
+		cmd := exec.Command("go", args...)
+		cmd.Dir = core.RootPath
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+
+	db, err := core.OpenDB(core.DB.DataType, core.BuildDSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	core.DB.Pool = db
+	return core.Seed(name)
+}
+
+func doMakeSeeder(name string) error {
+	if name == "" {
+		return errors.New("you must give the seeder a name")
+	}
+
+	if err := core.CreateDirIfNotExists(core.RootPath + "/seeds"); err != nil {
+		return err
+	}
+
+	seederName := strcase.ToSnake(name)
+
+	if hasFlag("sql") {
+		return copyFilefromTemplate("templates/seeds/seeder.sql", core.RootPath+"/seeds/"+seederName+".sql")
+	}
+
+	if err := copySeedsMain(); err != nil {
+		return err
+	}
+
+	fileName := core.RootPath + "/seeds/" + seederName + ".go"
+	if fileExists(fileName) {
+		return errors.New(fileName + " already exists!")
+	}
+
+	data, err := templateFS.ReadFile("templates/seeds/seeder.go.txt")
+	if err != nil {
+		return err
+	}
+
+	seeder := string(data)
+	seeder = strings.ReplaceAll(seeder, "$SEEDERNAME$", seederName)
+	seeder = strings.ReplaceAll(seeder, "$SEEDERFUNC$", strcase.ToLowerCamel("seed_"+seederName))
+
+	return os.WriteFile(fileName, []byte(seeder), 0644)
+}
+
+// copySeedsMain creates the program that runs the go seeders, if it does not exist yet
+func copySeedsMain() error {
+	mainFile := core.RootPath + "/seeds/main.go"
+	if fileExists(mainFile) {
+		return nil
+	}
+	return copyFilefromTemplate("templates/seeds/main.go.txt", mainFile)
+}
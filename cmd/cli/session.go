@@ -1,29 +1,12 @@
 package main
 
-import (
-	"fmt"
-	"time"
-)
-
 func doSessionTable() error {
-
-	dbType := core.DB.DataType
-
-	if dbType == "mariadb" {
-		dbType = "mysql"
-	}
-	if dbType == "postgresql" || dbType == "pgx" {
-		dbType = "postgres"
-	}
-	fileName := fmt.Sprintf("%d_create_sesssions_table", time.Now().UnixMicro())
-
-	upFile := core.RootPath + "/migrations/" + fileName + "." + dbType + ".up.sql"
-	downFile := core.RootPath + "/migrations/" + fileName + "." + dbType + ".down.sql"
-
-	if err := copyFilefromTemplate("templates/migrations/"+dbType+"/session.sql", upFile); err != nil {
+	up, err := migrationTemplate("session.sql")
+	if err != nil {
 		exitGracefully(err)
 	}
-	if err := copyDataToFile([]byte("drop table sessions"), downFile); err != nil {
+
+	if _, err := createMigration("create_sessions_table", up, "drop table sessions"); err != nil {
 		exitGracefully(err)
 	}
 
@@ -0,0 +1,2 @@
+-- ALTER TABLE `$TABLENAME$`
+--     ADD COLUMN `some_field` varchar(255) NOT NULL DEFAULT '';
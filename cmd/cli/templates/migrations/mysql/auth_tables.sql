@@ -7,6 +7,7 @@ CREATE TABLE `users` (
     `user_active` int(11) NOT NULL,
     `email` varchar(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
     `password` char(60) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `version` int(11) NOT NULL DEFAULT 1,
     `created_at` timestamp NULL DEFAULT NULL,
     `updated_at` timestamp NULL DEFAULT NULL,
     PRIMARY KEY (`id`),
@@ -0,0 +1,9 @@
+CREATE TABLE mail_queue (
+                          id VARCHAR(32) PRIMARY KEY,
+                          payload MEDIUMTEXT NOT NULL,
+                          not_before BIGINT NOT NULL,
+                          dead BOOLEAN NOT NULL DEFAULT FALSE,
+                          created_at TIMESTAMP(6) NOT NULL
+);
+
+CREATE INDEX mail_queue_ready_idx ON mail_queue (dead, not_before);
\ No newline at end of file
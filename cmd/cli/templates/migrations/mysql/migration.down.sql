@@ -0,0 +1 @@
+-- drop table some_table;
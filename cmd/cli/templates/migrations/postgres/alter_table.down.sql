@@ -0,0 +1,2 @@
+-- ALTER TABLE $TABLENAME$
+--     DROP COLUMN some_field;
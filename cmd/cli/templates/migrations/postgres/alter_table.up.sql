@@ -0,0 +1,2 @@
+-- ALTER TABLE $TABLENAME$
+--     ADD COLUMN some_field character varying(255) NOT NULL DEFAULT '';
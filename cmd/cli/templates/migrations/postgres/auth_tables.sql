@@ -15,6 +15,7 @@ CREATE TABLE users (
     user_active integer NOT NULL DEFAULT 0,
     email character varying(255) NOT NULL UNIQUE,
     password character varying(60) NOT NULL,
+    version integer NOT NULL DEFAULT 1,
     created_at timestamp without time zone NOT NULL DEFAULT now(),
     updated_at timestamp without time zone NOT NULL DEFAULT now()
 );
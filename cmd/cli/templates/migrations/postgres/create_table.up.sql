@@ -0,0 +1,20 @@
+CREATE TABLE $TABLENAME$ (
+    id SERIAL PRIMARY KEY,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    updated_at timestamp without time zone NOT NULL DEFAULT now()
+);
+
+-- add auto update of updated_at. If you already have this function
+-- you can delete the next 7 lines
+CREATE OR REPLACE FUNCTION trigger_set_timestamp()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at = NOW();
+RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON $TABLENAME$
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_set_timestamp();
@@ -0,0 +1,2 @@
+-- INSERT INTO some_table (some_field, created_at, updated_at)
+-- VALUES ('some value', now(), now());
@@ -12,6 +12,13 @@ func (c *Medego) OpenDB(dbType, dsn string) (*sql.DB, error) {
 	if dbType == "postgres" || dbType == "postgresql" {
 		dbType = "pgx"
 	}
+	if dbType == "mariadb" {
+		dbType = "mysql"
+	}
+	// github.com/mattn/go-sqlite3 registers itself as sqlite3
+	if dbType == "sqlite" {
+		dbType = "sqlite3"
+	}
 
 	db, err := sql.Open(dbType, dsn)
 	if err != nil {
@@ -0,0 +1,23 @@
+package medego
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMedego_OpenDB(t *testing.T) {
+	c := &Medego{}
+
+	for _, dbType := range []string{"sqlite", "sqlite3"} {
+		db, err := c.OpenDB(dbType, filepath.Join(t.TempDir(), "app.db"))
+		if err != nil {
+			t.Errorf("%s: %v", dbType, err)
+			continue
+		}
+		db.Close()
+	}
+
+	if _, err := c.OpenDB("unknown", ""); err == nil {
+		t.Error("expected an error for an unknown driver")
+	}
+}
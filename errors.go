@@ -0,0 +1,339 @@
+package medego
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/PrinMeshia/medego/render"
+)
+
+// ErrorReporter receives the server errors of requests, to send them to an error tracking service
+type ErrorReporter interface {
+	Report(r *http.Request, status int, err error)
+}
+
+// ErrorReporterFunc lets an ordinary function be used as an ErrorReporter
+type ErrorReporterFunc func(r *http.Request, status int, err error)
+
+// Report calls f(r, status, err)
+func (f ErrorReporterFunc) Report(r *http.Request, status int, err error) {
+	f(r, status, err)
+}
+
+// ProblemDetails is the body of API error responses, as described by RFC 7807
+type ProblemDetails struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Code     string            `json:"code,omitempty"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// PanicError is the error of a recovered panic, with the stack of the goroutine that panicked
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// RenderError answers a request with an error status. Server errors are logged and handed to the
+// ErrorReporter. API requests, under /api/ or asking for JSON, get problem details; other requests
+// get the developer page in debug mode, for server errors, or templates/errors/<status>.jet or
+// .page.tmpl when it exists, or else the status text.
+func (c *Medego) RenderError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	c.reportError(r, status, err)
+
+	if isAPIRequest(r) {
+		c.writeProblem(w, c.problem(r, status, err))
+		return
+	}
+
+	if c.Debug && status >= http.StatusInternalServerError && err != nil {
+		c.renderDeveloperError(w, r, status, err)
+		return
+	}
+
+	if c.renderErrorTemplate(w, r, status, err) {
+		return
+	}
+
+	http.Error(w, http.StatusText(status), status)
+}
+
+// reportError logs server errors and hands them to the ErrorReporter
+func (c *Medego) reportError(r *http.Request, status int, err error) {
+	if status < http.StatusInternalServerError || err == nil {
+		return
+	}
+
+	if c.ErrorLog != nil {
+		c.ErrorLog.Println(err)
+	}
+	if c.ErrorReporter != nil {
+		c.ErrorReporter.Report(r, status, err)
+	}
+}
+
+// writeProblem writes problem details as application/problem+json
+func (c *Medego) writeProblem(w http.ResponseWriter, problem ProblemDetails) {
+	out, err := json.MarshalIndent(problem, "", "\t")
+	if err != nil {
+		http.Error(w, http.StatusText(problem.Status), problem.Status)
+		return
+	}
+	c.setHeaders(w, problem.Status, "application/problem+json")
+	_, _ = w.Write(out)
+}
+
+// isAPIRequest reports whether the client of a request expects JSON rather than HTML
+func isAPIRequest(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return true
+	}
+
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+// renderErrorTemplate renders the error page of the status, and reports whether there was one.
+// The page gets the status in .Data.status and, for client errors, the message in .Data.error.
+func (c *Medego) renderErrorTemplate(w http.ResponseWriter, r *http.Request, status int, err error) bool {
+	// the default template data is read from the session, which is not loaded when the error
+	// happens before the session middleware: the status text is written then
+	if c.Render == nil || !c.sessionLoaded(r) {
+		return false
+	}
+
+	code := strconv.Itoa(status)
+	var renderPage func(buf *bytes.Buffer, td *render.TemplateData, vars jet.VarMap) error
+	switch {
+	case c.JetViews != nil && fileExists(c.Files("templates"), "errors/"+code+".jet"):
+		renderPage = func(buf *bytes.Buffer, td *render.TemplateData, vars jet.VarMap) error {
+			return c.Render.JetPage(buf, r, "errors/"+code, vars, td)
+		}
+	case fileExists(c.Files("templates"), "errors/"+code+".page.tmpl"):
+		renderPage = func(buf *bytes.Buffer, td *render.TemplateData, vars jet.VarMap) error {
+			return c.Render.GoPage(buf, r, "errors/"+code, td)
+		}
+	default:
+		return false
+	}
+
+	message := ""
+	var httpErr *HTTPError
+	switch {
+	case errors.As(err, &httpErr):
+		message = httpErr.Detail
+	case err != nil && status < http.StatusInternalServerError:
+		message = err.Error()
+	}
+
+	td := &render.TemplateData{Data: map[string]interface{}{"status": status, "error": message}}
+	vars := make(jet.VarMap)
+	vars.Set("status", status)
+	vars.Set("error", message)
+
+	buf := new(bytes.Buffer)
+	if err := renderPage(buf, td, vars); err != nil {
+		if c.ErrorLog != nil {
+			c.ErrorLog.Println("rendering error page:", err)
+		}
+		return false
+	}
+
+	c.setHeaders(w, status, "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+	return true
+}
+
+// renderDeveloperError shows the error with its stack trace, the request and the failing source code
+func (c *Medego) renderDeveloperError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	stack := debug.Stack()
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		stack = panicErr.Stack
+	}
+
+	dump, dumpErr := httputil.DumpRequest(r, false)
+	if dumpErr != nil {
+		dump = []byte(dumpErr.Error())
+	}
+
+	file, line := sourceFrame(stack)
+	page := developerPage{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Error:      err.Error(),
+		Stack:      string(stack),
+		Request:    string(dump),
+		File:       file,
+		Line:       line,
+		Source:     sourceLines(file, line, 5),
+	}
+
+	buf := new(bytes.Buffer)
+	if err := developerTemplate.Execute(buf, page); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	c.setHeaders(w, status, "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
+
+// sourceFrame returns the file and line of the first frame of a stack trace that is
+// application code, rather than the runtime, the standard library, chi or medego itself
+func sourceFrame(stack []byte) (string, int) {
+	lines := strings.Split(string(stack), "\n")
+	for i := 1; i+1 < len(lines); i++ {
+		function := lines[i]
+		if strings.HasPrefix(function, "\t") || function == "" {
+			continue
+		}
+
+		ignored := false
+		for _, prefix := range []string{"goroutine ", "runtime", "panic(", "net/http.", "testing.", "github.com/go-chi/", "github.com/PrinMeshia/medego."} {
+			if strings.HasPrefix(function, prefix) {
+				ignored = true
+				break
+			}
+		}
+		if ignored {
+			continue
+		}
+
+		// the next line is "\t/path/to/file.go:42 +0x1d"
+		location := strings.Fields(strings.TrimSpace(lines[i+1]))
+		if len(location) == 0 {
+			continue
+		}
+		sep := strings.LastIndex(location[0], ":")
+		if sep < 0 {
+			continue
+		}
+		file, lineNumber := location[0][:sep], location[0][sep+1:]
+		n, err := strconv.Atoi(lineNumber)
+		if err != nil {
+			continue
+		}
+		return file, n
+	}
+	return "", 0
+}
+
+type sourceLine struct {
+	Number  int
+	Code    string
+	Current bool
+}
+
+// sourceLines returns the lines of a file around a line
+func sourceLines(file string, line, context int) []sourceLine {
+	if file == "" {
+		return nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []sourceLine
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if n < line-context {
+			continue
+		}
+		if n > line+context {
+			break
+		}
+		lines = append(lines, sourceLine{Number: n, Code: scanner.Text(), Current: n == line})
+	}
+	return lines
+}
+
+type developerPage struct {
+	Status     int
+	StatusText string
+	Error      string
+	Stack      string
+	Request    string
+	File       string
+	Line       int
+	Source     []sourceLine
+}
+
+var developerTemplate = template.Must(template.New("developer").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Status}} {{.StatusText}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; color: #1f2328; }
+header { background: #b42318; color: #fff; padding: 1.5rem 2rem; }
+header h1 { margin: 0 0 .5rem; font-size: 1.25rem; }
+header p { margin: 0; font-family: ui-monospace, monospace; white-space: pre-wrap; }
+section { padding: 1rem 2rem; }
+h2 { font-size: 1rem; }
+pre { background: #f6f8fa; padding: 1rem; overflow: auto; font-size: .85rem; }
+.current { background: #ffebe9; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Status}} {{.StatusText}}</h1>
+<p>{{.Error}}</p>
+</header>
+{{if .Source}}
+<section>
+<h2>{{.File}}:{{.Line}}</h2>
+<pre>{{range .Source}}<span{{if .Current}} class="current"{{end}}>{{printf "%4d" .Number}}  {{.Code}}</span>
+{{end}}</pre>
+</section>
+{{end}}
+<section>
+<h2>Stack trace</h2>
+<pre>{{.Stack}}</pre>
+</section>
+<section>
+<h2>Request</h2>
+<pre>{{.Request}}</pre>
+</section>
+</body>
+</html>
+`))
+
+// Recoverer recovers from panics in handlers, answering with a 500 error through RenderError.
+// The error page is only rendered for the requests whose session was loaded, when Recoverer
+// runs after SessionLoad.
+func (c *Medego) Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+				c.RenderError(w, r, http.StatusInternalServerError, &PanicError{Value: rvr, Stack: debug.Stack()})
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
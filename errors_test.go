@@ -0,0 +1,76 @@
+package medego
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMedego_RenderError(t *testing.T) {
+	app := testApp()
+	app.FS = fstest.MapFS{
+		"templates/errors/404.page.tmpl": {Data: []byte(`<h1>Lost {{index .Data "status"}}</h1>`)},
+		"templates/errors/500.page.tmpl": {Data: []byte(`<h1>Broken {{index .Data "error"}}</h1>`)},
+	}
+	app.Render.FS, _ = fs.Sub(app.FS, "templates")
+
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.RenderError(w, r, http.StatusNotFound, nil)
+	})
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(errors.New("boom"))
+	})
+
+	var tests = []struct {
+		name        string
+		handler     http.Handler
+		path        string
+		accept      string
+		status      int
+		contentType string
+		expected    string
+	}{
+		{"error page", app.SessionLoad(notFound), "/", "text/html", http.StatusNotFound, "text/html; charset=utf-8", "<h1>Lost 404</h1>"},
+		{"session not loaded", notFound, "/", "text/html", http.StatusNotFound, "text/plain; charset=utf-8", "Not Found"},
+		{"api request", app.SessionLoad(notFound), "/api/users", "", http.StatusNotFound, "application/problem+json", `"status": 404`},
+		{"panic", app.SessionLoad(app.Recoverer(panicking)), "/", "text/html", http.StatusInternalServerError, "text/html; charset=utf-8", "<h1>Broken </h1>"},
+		{"panic before the session", app.Recoverer(app.SessionLoad(panicking)), "/", "text/html", http.StatusInternalServerError, "text/plain; charset=utf-8", "Internal Server Error"},
+	}
+
+	for _, e := range tests {
+		r := httptest.NewRequest(http.MethodGet, e.path, nil)
+		if e.accept != "" {
+			r.Header.Set("Accept", e.accept)
+		}
+		rr := httptest.NewRecorder()
+		e.handler.ServeHTTP(rr, r)
+
+		if rr.Code != e.status {
+			t.Errorf("%s: expected status %d, got %d", e.name, e.status, rr.Code)
+			continue
+		}
+		if rr.Header().Get("Content-Type") != e.contentType {
+			t.Errorf("%s: expected content type %s, got %s", e.name, e.contentType, rr.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(rr.Body.String(), e.expected) {
+			t.Errorf("%s: expected the body to contain %q, got %s", e.name, e.expected, rr.Body.String())
+		}
+	}
+}
+
+func TestMedego_ErrorStatus(t *testing.T) {
+	app := testApp()
+	rr := httptest.NewRecorder()
+	app.ErrorStatus(rr, http.StatusBadRequest)
+
+	if rr.Code != http.StatusBadRequest || rr.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("expected problem details with status 400, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rr.Body.String(), `"title": "Bad Request"`) {
+		t.Errorf("expected the status text as title, got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Event is a named message published on a bus, e.g. users.created
+type Event struct {
+	Name    string
+	Payload interface{}
+}
+
+// Handler is called synchronously for every event matching its subscription
+type Handler func(ctx context.Context, e Event)
+
+// Bus is an in-process publish/subscribe event bus
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]subscription
+	nextID   int
+}
+
+type subscription struct {
+	id      int
+	handler Handler
+}
+
+// Default is the bus used by the framework and the model repositories
+var Default = New()
+
+// New returns an empty bus
+func New() *Bus {
+	return &Bus{
+		handlers: make(map[string][]subscription),
+	}
+}
+
+// Subscribe calls handler for every event whose name matches pattern, and returns a function
+// that removes the subscription. A pattern is either an event name, a prefix ending with .*
+// such as users.*, or * for every event.
+func (b *Bus) Subscribe(pattern string, handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	b.handlers[pattern] = append(b.handlers[pattern], subscription{id: id, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		subs := b.handlers[pattern]
+		for i, s := range subs {
+			if s.id == id {
+				b.handlers[pattern] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish calls the handlers subscribed to the event, in the order they subscribed
+func (b *Bus) Publish(ctx context.Context, name string, payload interface{}) {
+	e := Event{Name: name, Payload: payload}
+	for _, h := range b.matching(name) {
+		h(ctx, e)
+	}
+}
+
+// HasSubscribers reports whether publishing the event would call any handler
+func (b *Bus) HasSubscribers(name string) bool {
+	return len(b.matching(name)) > 0
+}
+
+func (b *Bus) matching(name string) []Handler {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var subs []subscription
+	for pattern, s := range b.handlers {
+		if match(pattern, name) {
+			subs = append(subs, s...)
+		}
+	}
+
+	// handlers of different patterns run in subscription order
+	sort.Slice(subs, func(i, j int) bool { return subs[i].id < subs[j].id })
+
+	handlers := make([]Handler, len(subs))
+	for i, s := range subs {
+		handlers[i] = s.handler
+	}
+	return handlers
+}
+
+func match(pattern, name string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, ".*"):
+		return strings.HasPrefix(name, strings.TrimSuffix(pattern, "*"))
+	default:
+		return pattern == name
+	}
+}
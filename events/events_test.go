@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBus_Publish(t *testing.T) {
+	bus := New()
+
+	var got []string
+	record := func(prefix string) Handler {
+		return func(ctx context.Context, e Event) {
+			got = append(got, prefix+":"+e.Name)
+		}
+	}
+
+	bus.Subscribe("users.created", record("exact"))
+	unsubscribe := bus.Subscribe("users.*", record("prefix"))
+	bus.Subscribe("*", record("all"))
+
+	bus.Publish(context.Background(), "users.created", nil)
+	bus.Publish(context.Background(), "tokens.deleted", nil)
+
+	want := []string{"exact:users.created", "prefix:users.created", "all:users.created", "all:tokens.deleted"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	unsubscribe()
+	got = nil
+	bus.Publish(context.Background(), "users.updated", nil)
+
+	if !reflect.DeepEqual(got, []string{"all:users.updated"}) {
+		t.Errorf("unsubscribed handler called: %v", got)
+	}
+}
+
+func TestBus_HasSubscribers(t *testing.T) {
+	bus := New()
+	bus.Subscribe("users.*", func(ctx context.Context, e Event) {})
+
+	if !bus.HasSubscribers("users.deleted") {
+		t.Error("expected subscribers for users.deleted")
+	}
+	if bus.HasSubscribers("tokens.deleted") {
+		t.Error("unexpected subscribers for tokens.deleted")
+	}
+}
@@ -2,6 +2,7 @@ package medego
 
 import (
 	"crypto/rand"
+	"io/fs"
 	"os"
 )
 
@@ -43,3 +44,9 @@ func (c *Medego) CreateFileIfNotExists(path string) error {
 	}
 	return nil
 }
+
+// fileExists reports whether a regular file exists in a file system
+func fileExists(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && !info.IsDir()
+}
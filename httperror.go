@@ -0,0 +1,118 @@
+package medego
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HTTPError is an error with the HTTP status, and the details, of the response it should get.
+// Handlers return it, or pass it to WriteError, to answer with problem details such as
+//
+//	{"type": "about:blank", "title": "Unprocessable Entity", "status": 422, "code": "validation_failed",
+//	 "detail": "the submitted data is invalid", "errors": {"email": "Invalid email address"}}
+type HTTPError struct {
+	Status int
+	Code   string
+	Detail string
+	Fields map[string]string
+	Err    error
+}
+
+// NewHTTPError returns an error answered with status, a machine readable code and a message for the client
+func NewHTTPError(status int, code, detail string) *HTTPError {
+	return &HTTPError{
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// Error returns the detail of the error, or its status text, followed by the wrapped error
+func (e *HTTPError) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the error that caused the HTTP error
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of the error caused by err, which is logged but not shown to clients
+func (e *HTTPError) Wrap(err error) *HTTPError {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// HTTPError returns the errors of the validation as a 422 Unprocessable Entity error
+func (v *Validation) HTTPError() *HTTPError {
+	return &HTTPError{
+		Status: http.StatusUnprocessableEntity,
+		Code:   "validation_failed",
+		Detail: "the submitted data is invalid",
+		Fields: v.Errors,
+	}
+}
+
+// HandlerFunc is a handler that returns its errors instead of answering them itself
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// Handle adapts a handler returning errors to http.HandlerFunc. Returned errors are answered
+// with RenderError, using the status of an HTTPError, or 500 Internal Server Error.
+func (c *Medego) Handle(fn HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(w, r); err != nil {
+			c.RenderError(w, r, errorStatus(err), err)
+		}
+	}
+}
+
+// WriteError answers with the problem details of err as application/problem+json, whatever the
+// client accepts. The status is the status of an HTTPError, or 500 Internal Server Error.
+func (c *Medego) WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status := errorStatus(err)
+	c.reportError(r, status, err)
+	c.writeProblem(w, c.problem(r, status, err))
+}
+
+// errorStatus returns the status of an HTTPError, or 500
+func errorStatus(err error) int {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.Status != 0 {
+		return httpErr.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// problem returns the problem details of an error answered with status
+func (c *Medego) problem(r *http.Request, status int, err error) ProblemDetails {
+	problem := ProblemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: r.URL.Path,
+	}
+
+	var httpErr *HTTPError
+	switch {
+	case errors.As(err, &httpErr):
+		problem.Code = httpErr.Code
+		problem.Detail = httpErr.Detail
+		problem.Errors = httpErr.Fields
+		// the wrapped error may leak internals
+		if c.Debug && httpErr.Err != nil {
+			problem.Detail = httpErr.Error()
+		}
+	case err != nil && (status < http.StatusInternalServerError || c.Debug):
+		// the messages of server errors may leak internals
+		problem.Detail = err.Error()
+	}
+	return problem
+}
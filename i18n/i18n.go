@@ -0,0 +1,257 @@
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Translator translates message keys, replacing their {name} placeholders with
+// the values given as name, value pairs
+type Translator interface {
+	T(locale, key string, args ...interface{}) string
+}
+
+// I18n holds the message catalogs of the application, by locale
+type I18n struct {
+	DefaultLocale string
+
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+}
+
+// New returns an I18n without messages, falling back to defaultLocale
+func New(defaultLocale string) *I18n {
+	return &I18n{
+		DefaultLocale: Normalize(defaultLocale),
+		messages:      make(map[string]map[string]string),
+	}
+}
+
+// LoadDir loads the catalogs of a directory, one file per locale such as lang/en.json or
+// lang/pt-BR.toml. Nested keys are flattened with dots, so that {"validation": {"required": "..."}}
+// defines validation.required.
+func (i *I18n) LoadDir(dir string) error {
+	return i.LoadFS(os.DirFS(dir), ".")
+}
+
+// LoadFS loads the catalogs of a directory of a file system, such as an embed.FS, as LoadDir does
+func (i *I18n) LoadFS(fsys fs.FS, dir string) error {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		ext := path.Ext(file.Name())
+		if ext != ".json" && ext != ".toml" {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := i.Load(strings.TrimSuffix(file.Name(), ext), ext, content); err != nil {
+			return fmt.Errorf("%s: %w", file.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Load adds the messages of a catalog in the given format, .json or .toml, to a locale
+func (i *I18n) Load(locale, format string, content []byte) error {
+	var tree map[string]interface{}
+
+	switch strings.TrimPrefix(format, ".") {
+	case "json":
+		if err := json.Unmarshal(content, &tree); err != nil {
+			return err
+		}
+	case "toml":
+		if err := toml.Unmarshal(content, &tree); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported catalog format %q", format)
+	}
+
+	messages := make(map[string]string)
+	flatten("", tree, messages)
+	i.Add(locale, messages)
+	return nil
+}
+
+// Add adds messages to a locale, replacing the existing messages with the same keys
+func (i *I18n) Add(locale string, messages map[string]string) {
+	locale = Normalize(locale)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.messages[locale] == nil {
+		i.messages[locale] = make(map[string]string)
+	}
+	for key, message := range messages {
+		i.messages[locale][key] = message
+	}
+}
+
+// Locales returns the locales that have messages, sorted
+func (i *I18n) Locales() []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	locales := make([]string, 0, len(i.messages))
+	for locale := range i.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Lookup returns the message of a key in a locale, or else in its language, e.g. fr for fr-CA,
+// or else in the default locale
+func (i *I18n) Lookup(locale, key string) (string, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for _, l := range i.fallbacks(locale) {
+		if message, ok := i.messages[l][key]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// T translates a key, replacing the {name} placeholders of the message with the values given
+// as name, value pairs. The key itself is returned when no catalog has it.
+func (i *I18n) T(locale, key string, args ...interface{}) string {
+	message, ok := i.Lookup(locale, key)
+	if !ok {
+		message = key
+	}
+	return Format(message, args...)
+}
+
+// Match returns the supported locale that best matches an Accept-Language header,
+// e.g. fr-CH, fr;q=0.9, en;q=0.8, or an empty string when none does
+func (i *I18n) Match(acceptLanguage string) string {
+	type tag struct {
+		locale string
+		q      float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		params := strings.Split(part, ";")
+		t := tag{locale: Normalize(strings.TrimSpace(params[0])), q: 1}
+		for _, param := range params[1:] {
+			if key, value, _ := strings.Cut(strings.TrimSpace(param), "="); key == "q" {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					t.q = q
+				}
+			}
+		}
+		if t.locale != "" && t.locale != "*" && t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.SliceStable(tags, func(a, b int) bool { return tags[a].q > tags[b].q })
+
+	for _, t := range tags {
+		if locale := i.Supported(t.locale); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// Supported returns the locale, or its language, when it has messages, or else an empty string
+func (i *I18n) Supported(locale string) string {
+	locale = Normalize(locale)
+	language, _, _ := strings.Cut(locale, "-")
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for _, l := range []string{locale, language} {
+		if _, ok := i.messages[l]; ok {
+			return l
+		}
+	}
+	return ""
+}
+
+func (i *I18n) fallbacks(locale string) []string {
+	locale = Normalize(locale)
+	language, _, _ := strings.Cut(locale, "-")
+	return []string{locale, language, i.DefaultLocale}
+}
+
+// Normalize formats a locale as language-REGION, e.g. pt_br becomes pt-BR
+func Normalize(locale string) string {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	language, region, found := strings.Cut(locale, "-")
+	if !found {
+		return strings.ToLower(language)
+	}
+	return strings.ToLower(language) + "-" + strings.ToUpper(region)
+}
+
+// Format replaces the {name} placeholders of a message with the values given as name, value pairs
+func Format(message string, args ...interface{}) string {
+	if len(args) < 2 || !strings.Contains(message, "{") {
+		return message
+	}
+
+	pairs := make([]string, 0, len(args))
+	for n := 0; n+1 < len(args); n += 2 {
+		pairs = append(pairs, "{"+fmt.Sprint(args[n])+"}", fmt.Sprint(args[n+1]))
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+func flatten(prefix string, tree map[string]interface{}, messages map[string]string) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(key, v, messages)
+		case string:
+			messages[key] = v
+		default:
+			messages[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+type contextKey struct{}
+
+// WithLocale returns a copy of the context carrying the locale of a request
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// Locale returns the locale carried by a context, or an empty string
+func Locale(ctx context.Context) string {
+	locale, _ := ctx.Value(contextKey{}).(string)
+	return locale
+}
@@ -0,0 +1,66 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+)
+
+func TestI18n_LoadDir(t *testing.T) {
+	i := New("en")
+	if err := i.LoadDir("./testdata/lang"); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		locale   string
+		key      string
+		args     []interface{}
+		expected string
+	}{
+		{"en", "welcome", []interface{}{"name", "Ada"}, "Welcome, Ada!"},
+		{"fr", "welcome", []interface{}{"name", "Ada"}, "Bienvenue, Ada !"},
+		{"fr-CA", "validation.required", nil, "ce champ est obligatoire"},
+		{"de", "validation.required", nil, "this field cannot be blank"},
+		{"fr", "missing.key", nil, "missing.key"},
+	}
+
+	for _, e := range tests {
+		if got := i.T(e.locale, e.key, e.args...); got != e.expected {
+			t.Errorf("%s %s: expected %q, got %q", e.locale, e.key, e.expected, got)
+		}
+	}
+}
+
+func TestI18n_Match(t *testing.T) {
+	i := New("en")
+	i.Add("en", map[string]string{"hello": "Hello"})
+	i.Add("fr", map[string]string{"hello": "Bonjour"})
+	i.Add("pt_br", map[string]string{"hello": "Olá"})
+
+	var tests = []struct {
+		header   string
+		expected string
+	}{
+		{"fr-CH, fr;q=0.9, en;q=0.8", "fr"},
+		{"de, en;q=0.5", "en"},
+		{"en;q=0.2, pt-BR", "pt-BR"},
+		{"de", ""},
+		{"", ""},
+	}
+
+	for _, e := range tests {
+		if got := i.Match(e.header); got != e.expected {
+			t.Errorf("%q: expected %q, got %q", e.header, e.expected, got)
+		}
+	}
+}
+
+func TestLocale(t *testing.T) {
+	ctx := WithLocale(context.Background(), "fr")
+	if Locale(ctx) != "fr" {
+		t.Errorf("expected fr, got %q", Locale(ctx))
+	}
+	if Locale(context.Background()) != "" {
+		t.Error("expected no locale")
+	}
+}
@@ -0,0 +1,205 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	sparkpost "github.com/SparkPost/gosparkpost"
+	"github.com/mailgun/mailgun-go/v4"
+	"github.com/sendgrid/rest"
+	"github.com/sendgrid/sendgrid-go"
+	sendgridmail "github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+// apiTimeout is the time given to the APIs to accept a message
+const apiTimeout = 10 * time.Second
+
+// sendMailgun sends a prepared message with the Mailgun API. APIUrl is the base URL of the API,
+// e.g. https://api.eu.mailgun.net; inline attachments are referenced by their name.
+func (m *Mail) sendMailgun(msg Message, html, plain string) error {
+	mg := mailgun.NewMailgun(m.Domain, m.APIKey)
+	if base := strings.TrimSuffix(m.APIUrl, "/"); base != "" {
+		if !strings.HasSuffix(base, "/v3") {
+			base += "/v3"
+		}
+		mg.SetAPIBase(base)
+	}
+	if m.HTTPClient != nil {
+		mg.SetClient(m.HTTPClient)
+	}
+
+	message := mg.NewMessage(msg.from().String(), msg.Subject, plain, addressStrings(msg.To)...)
+	message.SetHtml(html)
+	for _, a := range msg.Cc {
+		message.AddCC(a.String())
+	}
+	for _, a := range msg.Bcc {
+		message.AddBCC(a.String())
+	}
+	if msg.ReplyTo.Email != "" {
+		message.SetReplyTo(msg.ReplyTo.String())
+	}
+	for name, value := range msg.Headers {
+		message.AddHeader(name, value)
+	}
+	for _, a := range msg.Attachments {
+		if a.Inline {
+			message.AddReaderInline(a.Name, io.NopCloser(bytes.NewReader(a.Data)))
+			continue
+		}
+		message.AddBufferAttachment(a.Name, a.Data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	_, _, err := mg.Send(ctx, message)
+	var unexpected *mailgun.UnexpectedResponseError
+	if errors.As(err, &unexpected) {
+		return statusError(unexpected.Actual, err)
+	}
+	return err
+}
+
+// sendSendGrid sends a prepared message with the SendGrid API. APIUrl is the host of the API,
+// https://api.sendgrid.com by default.
+func (m *Mail) sendSendGrid(msg Message, html, plain string) error {
+	email := sendgridmail.NewV3Mail()
+	email.SetFrom(sendgridmail.NewEmail(msg.FromName, msg.From))
+	email.Subject = msg.Subject
+	if msg.ReplyTo.Email != "" {
+		email.SetReplyTo(sendgridmail.NewEmail(msg.ReplyTo.Name, msg.ReplyTo.Email))
+	}
+	for name, value := range msg.Headers {
+		email.SetHeader(name, value)
+	}
+
+	p := sendgridmail.NewPersonalization()
+	p.AddTos(sendGridEmails(msg.To)...)
+	p.AddCCs(sendGridEmails(msg.Cc)...)
+	p.AddBCCs(sendGridEmails(msg.Bcc)...)
+	email.AddPersonalizations(p)
+
+	if plain != "" {
+		email.AddContent(sendgridmail.NewContent("text/plain", plain))
+	}
+	email.AddContent(sendgridmail.NewContent("text/html", html))
+
+	for _, a := range msg.Attachments {
+		attachment := sendgridmail.NewAttachment().
+			SetContent(base64.StdEncoding.EncodeToString(a.Data)).
+			SetType(a.ContentType).
+			SetFilename(a.Name).
+			SetDisposition("attachment")
+		if a.Inline {
+			attachment.SetDisposition("inline").SetContentID(a.Name)
+		}
+		email.AddAttachment(attachment)
+	}
+
+	request := sendgrid.GetRequest(m.APIKey, "/v3/mail/send", m.APIUrl)
+	request.Method = rest.Post
+	request.Body = sendgridmail.GetRequestBody(email)
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	client := &rest.Client{HTTPClient: m.httpClient()}
+	res, err := client.SendWithContext(ctx, request)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return statusError(res.StatusCode, fmt.Errorf("sendgrid: %d %s", res.StatusCode, res.Body))
+	}
+	return nil
+}
+
+// sendSparkPost sends a prepared message with the SparkPost API. APIUrl is the base URL of the API,
+// https://api.sparkpost.com by default. Cc and Bcc recipients get the message of the To recipients,
+// with the Cc header listing the Cc recipients.
+func (m *Mail) sendSparkPost(msg Message, html, plain string) error {
+	var client sparkpost.Client
+	if err := client.Init(&sparkpost.Config{BaseUrl: m.APIUrl, ApiKey: m.APIKey, ApiVersion: 1}); err != nil {
+		return Permanent(err)
+	}
+	client.Client = m.httpClient()
+
+	headerTo := strings.Join(addressStrings(msg.To), ", ")
+	var recipients []sparkpost.Recipient
+	for _, list := range [][]Address{msg.To, msg.Cc, msg.Bcc} {
+		for _, a := range list {
+			recipients = append(recipients, sparkpost.Recipient{
+				Address: sparkpost.Address{Email: a.Email, Name: a.Name, HeaderTo: headerTo},
+			})
+		}
+	}
+
+	content := sparkpost.Content{
+		From:    sparkpost.From{Email: msg.From, Name: msg.FromName},
+		Subject: msg.Subject,
+		HTML:    html,
+		Text:    plain,
+		Headers: make(map[string]string),
+	}
+	if msg.ReplyTo.Email != "" {
+		content.ReplyTo = msg.ReplyTo.String()
+	}
+	for name, value := range msg.Headers {
+		content.Headers[name] = value
+	}
+	if len(msg.Cc) > 0 {
+		content.Headers["CC"] = strings.Join(addressStrings(msg.Cc), ", ")
+	}
+	for _, a := range msg.Attachments {
+		attachment := sparkpost.Attachment{
+			MIMEType: a.ContentType,
+			Filename: a.Name,
+			B64Data:  base64.StdEncoding.EncodeToString(a.Data),
+		}
+		if a.Inline {
+			content.InlineImages = append(content.InlineImages, sparkpost.InlineImage(attachment))
+			continue
+		}
+		content.Attachments = append(content.Attachments, attachment)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	_, res, err := client.SendContext(ctx, &sparkpost.Transmission{Recipients: recipients, Content: content})
+	if err != nil && res != nil && res.HTTP != nil {
+		return statusError(res.HTTP.StatusCode, err)
+	}
+	return err
+}
+
+func (m *Mail) httpClient() *http.Client {
+	if m.HTTPClient != nil {
+		return m.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// statusError marks the errors of rejected requests permanent, except when rate limited
+func statusError(status int, err error) error {
+	if status >= http.StatusBadRequest && status < http.StatusInternalServerError && status != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
+
+func sendGridEmails(addresses []Address) []*sendgridmail.Email {
+	emails := make([]*sendgridmail.Email, len(addresses))
+	for n, a := range addresses {
+		emails[n] = sendgridmail.NewEmail(a.Name, a.Email)
+	}
+	return emails
+}
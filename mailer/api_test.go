@@ -0,0 +1,214 @@
+package mailer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testMessage() Message {
+	return Message{
+		To:       []Address{{Name: "You", Email: "you@there.com"}},
+		Cc:       Addresses("cc@there.com"),
+		Bcc:      Addresses("bcc@there.com"),
+		ReplyTo:  Address{Name: "Support", Email: "support@here.com"},
+		Headers:  map[string]string{"List-Unsubscribe": "<https://here.com/unsubscribe>"},
+		Subject:  "test",
+		Template: "test",
+		Attachments: []Attachment{
+			{Name: "logo.png", Data: []byte("\x89PNG"), Inline: true},
+			{Name: "report.txt", Data: []byte("report")},
+		},
+	}
+}
+
+// testAPI returns a mailer for an API served by handler, which gets the body of each request
+func testAPI(t *testing.T, api string, status int, handler func(r *http.Request, body string)) *Mail {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		handler(r, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, `{"id": "1", "message": "Queued", "results": {"id": "1"}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	return &Mail{
+		Domain:      "here.com",
+		Templates:   "./testdata/mail",
+		FromAddress: "me@here.com",
+		FromName:    "Joe",
+		API:         api,
+		APIKey:      "key",
+		APIUrl:      server.URL,
+		HTTPClient:  server.Client(),
+	}
+}
+
+func TestMail_SendUsingAPI_Mailgun(t *testing.T) {
+	var fields map[string][]string
+	var inline []string
+	m := testAPI(t, "mailgun", http.StatusOK, func(r *http.Request, body string) {
+		if r.URL.Path != "/v3/here.com/messages" {
+			t.Errorf("expected the messages endpoint of the domain, got %s", r.URL.Path)
+		}
+		fields = make(map[string][]string)
+		req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+		fields = req.MultipartForm.Value
+		for _, f := range req.MultipartForm.File["inline"] {
+			inline = append(inline, f.Filename)
+		}
+	})
+
+	if err := m.Send(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = map[string]string{
+		"from":               `"Joe" <me@here.com>`,
+		"to":                 `"You" <you@there.com>`,
+		"cc":                 "cc@there.com",
+		"bcc":                "bcc@there.com",
+		"h:Reply-To":         `"Support" <support@here.com>`,
+		"h:List-Unsubscribe": "<https://here.com/unsubscribe>",
+	}
+	for field, expected := range tests {
+		if len(fields[field]) != 1 || fields[field][0] != expected {
+			t.Errorf("expected %s to be %s, got %v", field, expected, fields[field])
+		}
+	}
+	if len(inline) != 1 || inline[0] != "logo.png" {
+		t.Errorf("expected the inline image, got %v", inline)
+	}
+}
+
+func TestMail_SendUsingAPI_SendGrid(t *testing.T) {
+	var sent struct {
+		Personalizations []struct {
+			To  []map[string]string `json:"to"`
+			Cc  []map[string]string `json:"cc"`
+			Bcc []map[string]string `json:"bcc"`
+		} `json:"personalizations"`
+		From        map[string]string   `json:"from"`
+		ReplyTo     map[string]string   `json:"reply_to"`
+		Headers     map[string]string   `json:"headers"`
+		Attachments []map[string]string `json:"attachments"`
+	}
+	m := testAPI(t, "sendgrid", http.StatusAccepted, func(r *http.Request, body string) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("expected the api key, got %s", r.Header.Get("Authorization"))
+		}
+		if err := json.Unmarshal([]byte(body), &sent); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if err := m.Send(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent.Personalizations) != 1 {
+		t.Fatalf("expected one personalization, got %d", len(sent.Personalizations))
+	}
+	p := sent.Personalizations[0]
+	if len(p.To) != 1 || p.To[0]["name"] != "You" || len(p.Cc) != 1 || len(p.Bcc) != 1 {
+		t.Errorf("expected the recipients, got %v", p)
+	}
+	if sent.From["email"] != "me@here.com" || sent.ReplyTo["email"] != "support@here.com" {
+		t.Errorf("expected the sender and reply-to, got %v and %v", sent.From, sent.ReplyTo)
+	}
+	if sent.Headers["List-Unsubscribe"] == "" {
+		t.Error("expected the List-Unsubscribe header")
+	}
+	if len(sent.Attachments) != 2 || sent.Attachments[0]["disposition"] != "inline" || sent.Attachments[0]["content_id"] != "logo.png" || sent.Attachments[1]["disposition"] != "attachment" {
+		t.Errorf("expected an inline image and an attachment, got %v", sent.Attachments)
+	}
+}
+
+func TestMail_SendUsingAPI_SparkPost(t *testing.T) {
+	var sent struct {
+		Recipients []struct {
+			Address map[string]string `json:"address"`
+		} `json:"recipients"`
+		Content struct {
+			ReplyTo      string              `json:"reply_to"`
+			Headers      map[string]string   `json:"headers"`
+			Attachments  []map[string]string `json:"attachments"`
+			InlineImages []map[string]string `json:"inline_images"`
+		} `json:"content"`
+	}
+	m := testAPI(t, "sparkpost", http.StatusOK, func(r *http.Request, body string) {
+		if err := json.Unmarshal([]byte(body), &sent); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if err := m.Send(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent.Recipients) != 3 {
+		t.Fatalf("expected the to, cc and bcc recipients, got %d", len(sent.Recipients))
+	}
+	for _, r := range sent.Recipients {
+		if r.Address["header_to"] != `"You" <you@there.com>` {
+			t.Errorf("expected every recipient to get the To header, got %v", r.Address)
+		}
+	}
+	if sent.Content.Headers["CC"] != "cc@there.com" || sent.Content.Headers["List-Unsubscribe"] == "" {
+		t.Errorf("expected the CC and List-Unsubscribe headers, got %v", sent.Content.Headers)
+	}
+	if sent.Content.ReplyTo != `"Support" <support@here.com>` {
+		t.Errorf("expected the reply-to, got %s", sent.Content.ReplyTo)
+	}
+	if len(sent.Content.InlineImages) != 1 || len(sent.Content.Attachments) != 1 || sent.Content.Attachments[0]["type"] != "text/plain; charset=utf-8" {
+		t.Errorf("expected an inline image and an attachment, got %v and %v", sent.Content.InlineImages, sent.Content.Attachments)
+	}
+}
+
+func TestMail_SendUsingAPI_Rejected(t *testing.T) {
+	m := testAPI(t, "sendgrid", http.StatusBadRequest, func(r *http.Request, body string) {})
+	if err := m.Send(testMessage()); !IsPermanent(err) {
+		t.Errorf("expected a permanent error when the API rejects the message, got %v", err)
+	}
+
+	m = testAPI(t, "sendgrid", http.StatusServiceUnavailable, func(r *http.Request, body string) {})
+	if err := m.Send(testMessage()); err == nil || IsPermanent(err) {
+		t.Errorf("expected a transient error when the API is unavailable, got %v", err)
+	}
+}
+
+func TestMail_prepare(t *testing.T) {
+	m := &Mail{FromAddress: "me@here.com", FromName: "Joe"}
+
+	msg, err := m.prepare(Message{
+		To:          Addresses("you@there.com"),
+		Attachments: []Attachment{AttachFile("./testdata/mail/test.plain.tmpl")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.from().String() != `"Joe" <me@here.com>` {
+		t.Errorf("expected the sender of the mailer, got %s", msg.from())
+	}
+	if a := msg.Attachments[0]; a.Name != "test.plain.tmpl" || len(a.Data) == 0 || a.ContentType != "application/octet-stream" {
+		t.Errorf("expected the attachment to be read from its file, got %s %s %d bytes", a.Name, a.ContentType, len(a.Data))
+	}
+
+	if _, err := m.prepare(Message{}); !errors.Is(err, errNoRecipient) || !IsPermanent(err) {
+		t.Errorf("expected a permanent error without recipient, got %v", err)
+	}
+	if _, err := m.prepare(Message{To: Addresses("you@there.com"), Attachments: []Attachment{AttachFile("missing.pdf")}}); !IsPermanent(err) {
+		t.Errorf("expected a permanent error for a missing attachment, got %v", err)
+	}
+}
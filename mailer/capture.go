@@ -0,0 +1,160 @@
+package mailer
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"mime"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultCaptureDir = "tmp/mail"
+
+// Captured is a message written by the file driver, in the files <ID>.eml, <ID>.html and <ID>.txt
+type Captured struct {
+	ID      string
+	From    string
+	To      string
+	Subject string
+	Date    time.Time
+}
+
+// SendToFile writes the message to Dir instead of sending it: the MIME message in a .eml file,
+// which mail clients open, and its HTML and plain text parts in .html and .txt files
+func (m *Mail) SendToFile(msg Message) error {
+	msg, err := m.prepare(msg)
+	if err != nil {
+		return err
+	}
+
+	formattedMessage, plainMessage, err := m.buildMessages(msg)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	email, err := m.buildEmail(msg, formattedMessage, plainMessage)
+	if err != nil {
+		return err
+	}
+
+	dir := m.captureDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	eml := email.DkimMsg
+	if eml == "" {
+		eml = email.GetMessage()
+	}
+
+	// the names sort by date
+	id := time.Now().Format("20060102-150405.000000") + "-" + newJobID()[:8]
+	files := map[string]string{
+		".eml":  eml,
+		".html": formattedMessage,
+		".txt":  plainMessage,
+	}
+	for ext, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, id+ext), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendToLog writes the recipients, subject and plain text of the message to Logger instead of
+// sending it
+func (m *Mail) SendToLog(msg Message) error {
+	msg, err := m.prepare(msg)
+	if err != nil {
+		return err
+	}
+
+	_, plainMessage, err := m.buildMessages(msg)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	logger := m.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "mail from %s to %s", msg.from(), strings.Join(addressStrings(msg.To), ", "))
+	if len(msg.Cc) > 0 {
+		fmt.Fprintf(&sb, ", cc %s", strings.Join(addressStrings(msg.Cc), ", "))
+	}
+	if len(msg.Bcc) > 0 {
+		fmt.Fprintf(&sb, ", bcc %s", strings.Join(addressStrings(msg.Bcc), ", "))
+	}
+	fmt.Fprintf(&sb, ": %s\n%s", msg.Subject, plainMessage)
+	logger.Println(sb.String())
+	return nil
+}
+
+// Captured returns the messages written by the file driver, newest first
+func (m *Mail) Captured() ([]Captured, error) {
+	paths, err := filepath.Glob(filepath.Join(m.captureDir(), "*.eml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var captured []Captured
+	for _, path := range paths {
+		c, err := readCaptured(path)
+		if err != nil {
+			continue
+		}
+		captured = append(captured, c)
+	}
+
+	sort.Slice(captured, func(i, j int) bool {
+		return captured[i].ID > captured[j].ID
+	})
+	return captured, nil
+}
+
+// readCaptured reads the headers of a message written by the file driver
+func readCaptured(path string) (Captured, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Captured{}, err
+	}
+	defer f.Close()
+
+	msg, err := netmail.ReadMessage(bufio.NewReader(f))
+	if err != nil {
+		return Captured{}, err
+	}
+
+	var decoder mime.WordDecoder
+	header := func(name string) string {
+		value, err := decoder.DecodeHeader(msg.Header.Get(name))
+		if err != nil {
+			return msg.Header.Get(name)
+		}
+		return value
+	}
+
+	date, _ := msg.Header.Date()
+	return Captured{
+		ID:      strings.TrimSuffix(filepath.Base(path), ".eml"),
+		From:    header("From"),
+		To:      header("To"),
+		Subject: header("Subject"),
+		Date:    date,
+	}, nil
+}
+
+func (m *Mail) captureDir() string {
+	if m.Dir == "" {
+		return defaultCaptureDir
+	}
+	return m.Dir
+}
@@ -0,0 +1,124 @@
+package mailer
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMail_SendToFile(t *testing.T) {
+	m := &Mail{
+		Templates:   "./testdata/mail",
+		FromAddress: "me@here.com",
+		API:         "file",
+		Dir:         t.TempDir(),
+	}
+
+	msg := testMessage()
+	msg.Subject = "Réinitialisation"
+	if err := m.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	captured, err := m.Captured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(captured) != 1 {
+		t.Fatalf("expected one captured message, got %d", len(captured))
+	}
+	c := captured[0]
+	if c.Subject != "Réinitialisation" || !strings.Contains(c.From, "me@here.com") || !strings.Contains(c.To, "you@there.com") || c.Date.IsZero() {
+		t.Errorf("expected the headers of the message, got %+v", c)
+	}
+
+	for _, ext := range []string{".eml", ".html", ".txt"} {
+		content, err := os.ReadFile(filepath.Join(m.Dir, c.ID+ext))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if ext == ".eml" && (!strings.Contains(string(content), "List-Unsubscribe") || !strings.Contains(string(content), "logo.png")) {
+			t.Errorf("expected the headers and attachments in the MIME message, got %s", content)
+		}
+		if ext == ".txt" && !strings.Contains(string(content), "Enter your message content here...") {
+			t.Errorf("expected the plain text part, got %s", content)
+		}
+	}
+}
+
+func TestMail_SendToLog(t *testing.T) {
+	buf := new(bytes.Buffer)
+	m := &Mail{
+		Templates:   "./testdata/mail",
+		FromAddress: "me@here.com",
+		API:         "log",
+		Logger:      log.New(buf, "", 0),
+	}
+
+	if err := m.Send(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	logged := buf.String()
+	for _, expected := range []string{`to "You" <you@there.com>`, "cc cc@there.com", "bcc bcc@there.com", ": test", "Enter your message content here..."} {
+		if !strings.Contains(logged, expected) {
+			t.Errorf("expected the log to contain %q, got %s", expected, logged)
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	m := &Mail{
+		Templates:   "./testdata/mail",
+		FromAddress: "me@here.com",
+		API:         "file",
+		Dir:         t.TempDir(),
+	}
+	if err := m.Send(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	captured, _ := m.Captured()
+	preview := &Preview{Mail: m}
+
+	var tests = []struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+		expected    string
+	}{
+		{"index", "/", http.StatusOK, "text/html; charset=utf-8", "messages/" + captured[0].ID + ".html"},
+		{"index templates", "/", http.StatusOK, "text/html; charset=utf-8", "templates/preview"},
+		{"html part", "/messages/" + captured[0].ID + ".html", http.StatusOK, "text/html; charset=utf-8", "Enter your message content here..."},
+		{"mime message", "/messages/" + captured[0].ID + ".eml", http.StatusOK, "message/rfc822", "Subject: test"},
+		{"other file", "/messages/" + captured[0].ID + ".json", http.StatusNotFound, "", ""},
+		{"outside the folder", "/messages/..%2Fsecret.txt", http.StatusNotFound, "", ""},
+		{"sample data", "/templates/preview", http.StatusOK, "text/html; charset=utf-8", `Hello Joe, <a href="https://example.com/reset">`},
+		{"query data", "/templates/preview?format=plain&Name=Ann", http.StatusOK, "text/plain; charset=utf-8", "Hello Ann, https://example.com/reset"},
+		{"markdown template", "/", http.StatusOK, "text/html; charset=utf-8", "templates/notice"},
+		{"markdown plain text", "/templates/notice?format=plain&Name=Ann&Link=https://example.com", http.StatusOK, "text/plain; charset=utf-8", "- Track it (https://example.com)"},
+		{"unknown template", "/templates/missing", http.StatusNotFound, "", ""},
+	}
+
+	for _, e := range tests {
+		rr := httptest.NewRecorder()
+		preview.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, e.path, nil))
+
+		if rr.Code != e.status {
+			t.Errorf("%s: expected status %d, got %d", e.name, e.status, rr.Code)
+			continue
+		}
+		if e.contentType != "" && rr.Header().Get("Content-Type") != e.contentType {
+			t.Errorf("%s: expected content type %s, got %s", e.name, e.contentType, rr.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(rr.Body.String(), e.expected) {
+			t.Errorf("%s: expected the body to contain %q, got %s", e.name, e.expected, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+
+	"github.com/toorop/go-dkim"
+)
+
+// defaultDKIMHeaders are the headers signed by default
+var defaultDKIMHeaders = []string{"from", "to", "cc", "reply-to", "subject", "date", "message-id", "mime-version", "content-type"}
+
+// errDKIMKey is returned for a private key which is not a PEM RSA key
+var errDKIMKey = errors.New("mailer: the DKIM private key must be a PEM encoded RSA key")
+
+// DKIM signs the messages sent with SMTP, or written by the file driver; the API services sign
+// the messages with the keys set up on their side. The public key is published in the TXT record
+// <selector>._domainkey.<domain>.
+type DKIM struct {
+	Domain   string
+	Selector string
+	// PrivateKey is the PEM encoded RSA private key, in PKCS #1 or PKCS #8 form
+	PrivateKey []byte
+	// Headers are the signed headers, the From, To, Cc, Reply-To, Subject, Date, Message-Id and
+	// content headers by default
+	Headers []string
+}
+
+// options returns the signing options, once the private key is checked
+func (d *DKIM) options() (dkim.SigOptions, error) {
+	block, _ := pem.Decode(d.PrivateKey)
+	if block == nil {
+		return dkim.SigOptions{}, errDKIMKey
+	}
+	if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return dkim.SigOptions{}, errDKIMKey
+		}
+		if _, ok := key.(*rsa.PrivateKey); !ok {
+			return dkim.SigOptions{}, errDKIMKey
+		}
+	}
+
+	options := dkim.NewSigOptions()
+	options.Domain = d.Domain
+	options.Selector = d.Selector
+	options.PrivateKey = d.PrivateKey
+	options.Canonicalization = "relaxed/relaxed"
+	headers := d.Headers
+	if len(headers) == 0 {
+		headers = defaultDKIMHeaders
+	}
+	// the signature lowercases the headers in place
+	options.Headers = append([]string(nil), headers...)
+	return options, nil
+}
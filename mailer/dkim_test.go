@@ -0,0 +1,113 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/toorop/go-dkim"
+)
+
+var testDKIMKey *rsa.PrivateKey
+
+// testDKIM returns DKIM settings with a new PKCS #8 key
+func testDKIM(t *testing.T) *DKIM {
+	t.Helper()
+
+	if testDKIMKey == nil {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testDKIMKey = key
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(testDKIMKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &DKIM{
+		Domain:     "here.com",
+		Selector:   "mail",
+		PrivateKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+	}
+}
+
+// verifyDKIM checks the signature of a message with the public key of the settings
+func verifyDKIM(t *testing.T, d *DKIM, message string) {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(&testDKIMKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := func(name string) ([]string, error) {
+		if name != d.Selector+"._domainkey."+d.Domain {
+			return nil, errors.New("no record for " + name)
+		}
+		return []string{"v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der)}, nil
+	}
+
+	email := []byte(message)
+	status, err := dkim.Verify(&email, dkim.DNSOptLookupTXT(lookup))
+	if err != nil || status != dkim.SUCCESS {
+		t.Errorf("expected a valid DKIM signature, got status %d: %v", status, err)
+	}
+}
+
+func TestMail_SendToFile_DKIM(t *testing.T) {
+	m := &Mail{
+		Templates:   "./testdata/mail",
+		FromAddress: "me@here.com",
+		API:         "file",
+		Dir:         t.TempDir(),
+		DKIM:        testDKIM(t),
+	}
+
+	if err := m.Send(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	captured, err := m.Captured()
+	if err != nil || len(captured) != 1 {
+		t.Fatalf("expected one captured message, got %d: %v", len(captured), err)
+	}
+	content, err := os.ReadFile(filepath.Join(m.Dir, captured[0].ID+".eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyDKIM(t, m.DKIM, string(content))
+}
+
+func TestDKIM_options(t *testing.T) {
+	d := testDKIM(t)
+	options, err := d.options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Domain != "here.com" || options.Selector != "mail" || len(options.Headers) != len(defaultDKIMHeaders) {
+		t.Errorf("expected the settings and the default headers, got %+v", options)
+	}
+
+	der := x509.MarshalPKCS1PrivateKey(testDKIMKey)
+	d.PrivateKey = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der})
+	if _, err := d.options(); err != nil {
+		t.Errorf("expected a PKCS #1 key to be accepted, got %v", err)
+	}
+
+	d.PrivateKey = []byte("not a key")
+	if _, err := d.options(); !errors.Is(err, errDKIMKey) {
+		t.Errorf("expected an error for an invalid key, got %v", err)
+	}
+
+	m := &Mail{Templates: "./testdata/mail", FromAddress: "me@here.com", API: "file", Dir: t.TempDir(), DKIM: d}
+	if err := m.Send(testMessage()); !IsPermanent(err) {
+		t.Errorf("expected a permanent error for an invalid key, got %v", err)
+	}
+}
@@ -2,32 +2,37 @@ package mailer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"os"
-	"path/filepath"
-	"time"
+	"strings"
 
-	apimail "github.com/ainsleyclark/go-mail"
+	"github.com/PrinMeshia/medego/i18n"
 	"github.com/vanng822/go-premailer/premailer"
 	mail "github.com/xhit/go-simple-mail/v2"
 )
 
-// ListenForMail listens to the mail channel and sends mail
+// ListenForMail starts the workers of the queue, and queues the messages sent on the Jobs channel
 func (m *Mail) ListenForMail() {
-	for {
-		msg := <-m.Jobs
-		err := m.Send(msg)
-		if err != nil {
-			m.Results <- Result{false, err}
-		} else {
-			m.Results <- Result{true, nil}
+	m.Start()
+	for msg := range m.Jobs {
+		if _, err := m.Enqueue(msg); err != nil {
+			m.report(Job{Message: msg}, Result{Success: false, Error: err})
 		}
 	}
 }
 
 // Send sends an email message using the correct method
 func (m *Mail) Send(msg Message) error {
+	switch m.API {
+	case "file":
+		return m.SendToFile(msg)
+	case "log":
+		return m.SendToLog(msg)
+	}
+
 	if len(m.API) > 0 && len(m.APIKey) > 0 && len(m.APIUrl) > 0 && m.API != "smtp" {
 		return m.ChooseAPI(msg)
 	}
@@ -40,115 +45,118 @@ func (m *Mail) ChooseAPI(msg Message) error {
 	case "mailgun", "sparkpost", "sendgrid":
 		return m.SendUsingAPI(msg)
 	default:
-		return fmt.Errorf("unknown API %s; only mailgun, sparkpost or sendgrid accepted", m.API)
+		return Permanent(fmt.Errorf("unknown API %s; only mailgun, sparkpost or sendgrid accepted", m.API))
 	}
 }
 
 // SendUsingAPI sends a message using the appropriate API
 func (m *Mail) SendUsingAPI(msg Message) error {
-	if msg.From == "" {
-		msg.From = m.FromAddress
-	}
-
-	if msg.FromName == "" {
-		msg.FromName = m.FromName
-	}
-
-	cfg := apimail.Config{
-		URL:         m.APIUrl,
-		APIKey:      m.APIKey,
-		Domain:      m.Domain,
-		FromAddress: msg.From,
-		FromName:    msg.FromName,
-	}
-
-	driver, err := apimail.NewClient(m.API, cfg)
+	msg, err := m.prepare(msg)
 	if err != nil {
 		return err
 	}
 
 	formattedMessage, plainMessage, err := m.buildMessages(msg)
 	if err != nil {
-		return err
+		return Permanent(err)
 	}
 
-	tx := &apimail.Transmission{
-		Recipients: []string{msg.To},
-		Subject:    msg.Subject,
-		HTML:       formattedMessage,
-		PlainText:  plainMessage,
+	switch m.API {
+	case "mailgun":
+		return m.sendMailgun(msg, formattedMessage, plainMessage)
+	case "sendgrid":
+		return m.sendSendGrid(msg, formattedMessage, plainMessage)
+	case "sparkpost":
+		return m.sendSparkPost(msg, formattedMessage, plainMessage)
+	default:
+		return Permanent(fmt.Errorf("unknown API %s; only mailgun, sparkpost or sendgrid accepted", m.API))
 	}
+}
 
-	err = m.addAPIAttachments(msg, tx)
+// SendSMTPMessage builds and sends an email message using SMTP
+func (m *Mail) SendSMTPMessage(msg Message) error {
+	msg, err := m.prepare(msg)
 	if err != nil {
 		return err
 	}
 
-	_, err = driver.Send(tx)
-	return err
-}
-
-// addAPIAttachments adds attachments to mail being sent via API
-func (m *Mail) addAPIAttachments(msg Message, tx *apimail.Transmission) error {
-	if len(msg.Attachments) > 0 {
-		var attachments []apimail.Attachment
-
-		for _, x := range msg.Attachments {
-			content, err := os.ReadFile(x)
-			if err != nil {
-				return err
-			}
-
-			fileName := filepath.Base(x)
-			attachments = append(attachments, apimail.Attachment{Bytes: content, Filename: fileName})
-		}
-
-		tx.Attachments = attachments
+	formattedMessage, plainMessage, err := m.buildMessages(msg)
+	if err != nil {
+		return Permanent(err)
 	}
 
-	return nil
-}
-
-// SendSMTPMessage builds and sends an email message using SMTP
-func (m *Mail) SendSMTPMessage(msg Message) error {
-	formattedMessage, plainMessage, err := m.buildMessages(msg)
+	email, err := m.buildEmail(msg, formattedMessage, plainMessage)
 	if err != nil {
 		return err
 	}
 
-	server := mail.NewSMTPClient()
-	server.Host = m.Host
-	server.Port = m.Port
-	server.Username = m.Username
-	server.Password = m.Password
-	server.Encryption = m.getEncryption(m.Encryption)
-	server.KeepAlive = false
-	server.ConnectTimeout = 10 * time.Second
-	server.SendTimeout = 10 * time.Second
-
-	smtpClient, err := server.Connect()
+	conn, err := m.smtpConn()
 	if err != nil {
 		return err
 	}
+	if err := email.Send(conn.client); err != nil {
+		// the connection may be broken: the next message opens a new one
+		conn.close()
+		return err
+	}
+	m.releaseSMTP(conn)
+	return nil
+}
 
+// buildEmail creates the MIME message of a prepared message
+func (m *Mail) buildEmail(msg Message, formattedMessage, plainMessage string) (*mail.Email, error) {
 	email := mail.NewMSG().
-		SetFrom(msg.From).
-		AddTo(msg.To).
+		SetFrom(msg.from().String()).
 		SetSubject(msg.Subject).
 		SetBody(mail.TextHTML, formattedMessage).
 		AddAlternative(mail.TextPlain, plainMessage)
 
-	for _, x := range msg.Attachments {
-		email.AddAttachment(x)
+	if len(msg.To) > 0 {
+		email.AddTo(addressStrings(msg.To)...)
+	}
+	if len(msg.Cc) > 0 {
+		email.AddCc(addressStrings(msg.Cc)...)
+	}
+	if len(msg.Bcc) > 0 {
+		email.AddBcc(addressStrings(msg.Bcc)...)
+	}
+	if msg.ReplyTo.Email != "" {
+		email.SetReplyTo(msg.ReplyTo.String())
+	}
+	for name, value := range msg.Headers {
+		email.AddHeader(name, value)
+	}
+	for _, a := range msg.Attachments {
+		email.Attach(&mail.File{Name: a.Name, MimeType: a.ContentType, Data: a.Data, Inline: a.Inline})
+	}
+
+	if m.DKIM != nil {
+		options, err := m.DKIM.options()
+		if err != nil {
+			return nil, Permanent(err)
+		}
+		email.SetDkim(options)
 	}
 
-	return email.Send(smtpClient)
+	// invalid addresses, headers, or DKIM settings
+	if email.Error != nil {
+		return nil, Permanent(email.Error)
+	}
+	return email, nil
 }
 
-// buildMessages creates both the HTML and plaintext versions of the message
+// buildMessages creates both the HTML and plaintext versions of the message, from its Markdown
+// template when it has no HTML one
 func (m *Mail) buildMessages(msg Message) (string, string, error) {
-	htmlTemplate := fmt.Sprintf("%s/%s.html.tmpl", m.Templates, msg.Template)
-	plainTemplate := fmt.Sprintf("%s/%s.plain.tmpl", m.Templates, msg.Template)
+	htmlTemplate := m.templatePath(msg, "html")
+	plainTemplate := m.templatePath(msg, "plain")
+
+	if _, err := fs.Stat(m.templates(), htmlTemplate); errors.Is(err, fs.ErrNotExist) {
+		markdownTemplate := m.templatePath(msg, "md")
+		if _, err := fs.Stat(m.templates(), markdownTemplate); err == nil {
+			return m.buildMarkdown(markdownTemplate, msg)
+		}
+	}
 
 	htmlMessage, err := m.buildMessage(htmlTemplate, msg)
 	if err != nil {
@@ -163,9 +171,38 @@ func (m *Mail) buildMessages(msg Message) (string, string, error) {
 	return htmlMessage, plainMessage, nil
 }
 
+// templatePath returns the template of a message in its locale, e.g. welcome.fr-CA.html.tmpl,
+// or else in its language, e.g. welcome.fr.html.tmpl, or else welcome.html.tmpl
+func (m *Mail) templatePath(msg Message, kind string) string {
+	if msg.Locale != "" {
+		locale := i18n.Normalize(msg.Locale)
+		language, _, _ := strings.Cut(locale, "-")
+		for _, l := range []string{locale, language} {
+			path := fmt.Sprintf("%s.%s.%s.tmpl", msg.Template, l, kind)
+			if _, err := fs.Stat(m.templates(), path); err == nil {
+				return path
+			}
+		}
+	}
+	return fmt.Sprintf("%s.%s.tmpl", msg.Template, kind)
+}
+
+// templates returns the folder of the templates: FS, or else the Templates folder on disk
+func (m *Mail) templates() fs.FS {
+	if m.FS != nil {
+		return m.FS
+	}
+	return os.DirFS(m.Templates)
+}
+
 // buildMessage creates either the HTML or plaintext version of the message
 func (m *Mail) buildMessage(templatePath string, msg Message) (string, error) {
-	t, err := template.New("email-template").ParseFiles(templatePath)
+	content, err := fs.ReadFile(m.templates(), templatePath)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := template.New("email-template").Funcs(m.templateFuncs(msg)).Parse(string(content))
 	if err != nil {
 		return "", err
 	}
@@ -177,7 +214,7 @@ func (m *Mail) buildMessage(templatePath string, msg Message) (string, error) {
 
 	message := tpl.String()
 
-	if templatePath == "html" {
+	if strings.HasSuffix(templatePath, ".html.tmpl") {
 		message, err = m.inlineCSS(message)
 		if err != nil {
 			return "", err
@@ -187,6 +224,18 @@ func (m *Mail) buildMessage(templatePath string, msg Message) (string, error) {
 	return message, nil
 }
 
+// templateFuncs returns the functions of the templates of a message
+func (m *Mail) templateFuncs(msg Message) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, args ...interface{}) string {
+			if m.Translator == nil {
+				return i18n.Format(key, args...)
+			}
+			return m.Translator.T(msg.Locale, key, args...)
+		},
+	}
+}
+
 // getEncryption returns the appropriate encryption type based on a string value
 func (m *Mail) getEncryption(e string) mail.Encryption {
 	switch e {
@@ -2,17 +2,22 @@ package mailer
 
 import (
 	"errors"
+	"strings"
 	"testing"
+
+	"github.com/PrinMeshia/medego/i18n"
 )
 
 func TestMail_SendSMTPMessage(t *testing.T) {
+	requireMailhog(t)
+
 	msg := Message{
 		From:        "me@here.com",
 		FromName:    "Joe",
-		To:          "you@there.com",
+		To:          Addresses("you@there.com"),
 		Subject:     "test",
 		Template:    "test",
-		Attachments: []string{"./testdata/mail/test.html.tmpl"},
+		Attachments: []Attachment{AttachFile("./testdata/mail/test.html.tmpl")},
 	}
 
 	err := mailer.SendSMTPMessage(msg)
@@ -21,14 +26,42 @@ func TestMail_SendSMTPMessage(t *testing.T) {
 	}
 }
 
+func TestMail_SendSMTPMessageRecipients(t *testing.T) {
+	requireMailhog(t)
+
+	logo, err := AttachReader("logo.png", "image/png", strings.NewReader("\x89PNG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logo.Inline = true
+
+	msg := Message{
+		To:          []Address{{Name: "You", Email: "you@there.com"}, {Email: "other@there.com"}},
+		Cc:          Addresses("cc@there.com"),
+		Bcc:         Addresses("bcc@there.com"),
+		ReplyTo:     Address{Name: "Support", Email: "support@here.com"},
+		Headers:     map[string]string{"List-Unsubscribe": "<https://here.com/unsubscribe>"},
+		Subject:     "test",
+		Template:    "test",
+		Attachments: []Attachment{logo, {Name: "report.txt", Data: []byte("report")}},
+	}
+
+	// the sender defaults to the address of the mailer
+	if err := mailer.SendSMTPMessage(msg); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestMail_SendUsingChan(t *testing.T) {
+	requireMailhog(t)
+
 	msg := Message{
 		From:        "me@here.com",
 		FromName:    "Joe",
-		To:          "you@there.com",
+		To:          Addresses("you@there.com"),
 		Subject:     "test",
 		Template:    "test",
-		Attachments: []string{"./testdata/mail/test.html.tmpl"},
+		Attachments: []Attachment{AttachFile("./testdata/mail/test.html.tmpl")},
 	}
 
 	mailer.Jobs <- msg
@@ -37,7 +70,7 @@ func TestMail_SendUsingChan(t *testing.T) {
 		t.Error(errors.New("failed to send over channel"))
 	}
 
-	msg.To = "not_an_email_address"
+	msg.To = Addresses("not_an_email_address")
 	mailer.Jobs <- msg
 	res = <-mailer.Results
 	if res.Error == nil {
@@ -47,10 +80,10 @@ func TestMail_SendUsingChan(t *testing.T) {
 
 func TestMail_SendUsingAPI(t *testing.T) {
 	msg := Message{
-		To:          "you@there.com",
+		To:          Addresses("you@there.com"),
 		Subject:     "test",
 		Template:    "test",
-		Attachments: []string{"./testdata/mail/test.html.tmpl"},
+		Attachments: []Attachment{AttachFile("./testdata/mail/test.html.tmpl")},
 	}
 
 	mailer.API = "unknown"
@@ -70,10 +103,10 @@ func TestMail_buildMessages(t *testing.T) {
 	msg := Message{
 		From:        "me@here.com",
 		FromName:    "Joe",
-		To:          "you@there.com",
+		To:          Addresses("you@there.com"),
 		Subject:     "test",
 		Template:    "test",
-		Attachments: []string{"./testdata/mail/test.html.tmpl"},
+		Attachments: []Attachment{AttachFile("./testdata/mail/test.html.tmpl")},
 	}
 
 	_, _, err := mailer.buildMessages(msg)
@@ -82,14 +115,45 @@ func TestMail_buildMessages(t *testing.T) {
 	}
 }
 
+func TestMail_buildMessagesLocale(t *testing.T) {
+	translations := i18n.New("en")
+	translations.Add("fr", map[string]string{"greeting": "Bonjour"})
+	mailer.Translator = translations
+	defer func() { mailer.Translator = nil }()
+
+	var tests = []struct {
+		locale   string
+		expected string
+	}{
+		{"fr-CA", "Bonjour"},
+		{"de", "Enter your message content here..."},
+	}
+
+	for _, e := range tests {
+		html, plain, err := mailer.buildMessages(Message{Template: "test", Locale: e.locale})
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !strings.Contains(html, e.expected) {
+			t.Errorf("%s: expected the html message to contain %q, got %q", e.locale, e.expected, html)
+		}
+		if !strings.Contains(plain, "Enter your message content here...") {
+			t.Errorf("%s: expected the default plain message, got %q", e.locale, plain)
+		}
+	}
+}
+
 func TestMail_send(t *testing.T) {
+	requireMailhog(t)
+
 	msg := Message{
 		From:        "me@here.com",
 		FromName:    "Joe",
-		To:          "you@there.com",
+		To:          Addresses("you@there.com"),
 		Subject:     "test",
 		Template:    "test",
-		Attachments: []string{"./testdata/mail/test.html.tmpl"},
+		Attachments: []Attachment{AttachFile("./testdata/mail/test.html.tmpl")},
 	}
 
 	err := mailer.Send(msg)
@@ -115,10 +179,10 @@ func TestMail_ChooseAPI(t *testing.T) {
 	msg := Message{
 		From:        "me@here.com",
 		FromName:    "Joe",
-		To:          "you@there.com",
+		To:          Addresses("you@there.com"),
 		Subject:     "test",
 		Template:    "test",
-		Attachments: []string{"./testdata/mail/test.html.tmpl"},
+		Attachments: []Attachment{AttachFile("./testdata/mail/test.html.tmpl")},
 	}
 	mailer.API = "unknown"
 	err := mailer.ChooseAPI(msg)
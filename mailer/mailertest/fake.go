@@ -0,0 +1,167 @@
+// Package mailertest provides a fake mailer for the tests of apps sending mail.
+//
+//	fake := &mailertest.Fake{}
+//	app.Mail = fake
+//	// call the handler
+//	fake.AssertSentTo(t, "you@there.com")
+//	fake.AssertTemplate(t, "password-reset")
+package mailertest
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PrinMeshia/medego/mailer"
+)
+
+// Fake is a mailer.Sender recording the messages instead of sending them
+type Fake struct {
+	// Err is returned by Send, which then records nothing, e.g. to test a mail server failure
+	Err error
+
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+var _ mailer.Sender = (*Fake)(nil)
+
+// Send records the message
+func (f *Fake) Send(msg mailer.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return f.Err
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+// Sent returns the recorded messages, in the order they were sent
+func (f *Fake) Sent() []mailer.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sent := make([]mailer.Message, len(f.sent))
+	copy(sent, f.sent)
+	return sent
+}
+
+// Reset forgets the recorded messages
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = nil
+}
+
+// AssertCount fails the test unless n messages were sent
+func (f *Fake) AssertCount(t testing.TB, n int) {
+	t.Helper()
+	if sent := f.Sent(); len(sent) != n {
+		t.Errorf("expected %d messages to be sent, got %d", n, len(sent))
+	}
+}
+
+// AssertNothingSent fails the test when a message was sent
+func (f *Fake) AssertNothingSent(t testing.TB) {
+	t.Helper()
+	f.AssertCount(t, 0)
+}
+
+// AssertSentTo fails the test unless a message was sent to the email, as a To, Cc or Bcc
+// recipient, and returns the last one
+func (f *Fake) AssertSentTo(t testing.TB, email string) mailer.Message {
+	t.Helper()
+	msg, ok := f.last(func(msg mailer.Message) bool {
+		for _, list := range [][]mailer.Address{msg.To, msg.Cc, msg.Bcc} {
+			for _, a := range list {
+				if strings.EqualFold(a.Email, email) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+	if !ok {
+		t.Errorf("expected a message to be sent to %s, got %s", email, f.recipients())
+	}
+	return msg
+}
+
+// AssertTemplate fails the test unless a message was built from the template, and returns the
+// last one
+func (f *Fake) AssertTemplate(t testing.TB, template string) mailer.Message {
+	t.Helper()
+	msg, ok := f.last(func(msg mailer.Message) bool {
+		return msg.Template == template
+	})
+	if !ok {
+		var templates []string
+		for _, msg := range f.Sent() {
+			templates = append(templates, msg.Template)
+		}
+		t.Errorf("expected a message with the template %s, got %v", template, templates)
+	}
+	return msg
+}
+
+// AssertAttachment fails the test unless a message was sent with an attachment of the name, and
+// returns the last one
+func (f *Fake) AssertAttachment(t testing.TB, name string) mailer.Message {
+	t.Helper()
+	msg, ok := f.last(func(msg mailer.Message) bool {
+		for _, a := range msg.Attachments {
+			if attachmentName(a) == name {
+				return true
+			}
+		}
+		return false
+	})
+	if !ok {
+		var names []string
+		for _, msg := range f.Sent() {
+			for _, a := range msg.Attachments {
+				names = append(names, attachmentName(a))
+			}
+		}
+		t.Errorf("expected a message with the attachment %s, got %v", name, names)
+	}
+	return msg
+}
+
+// last returns the last message sent matching the function
+func (f *Fake) last(match func(mailer.Message) bool) (mailer.Message, bool) {
+	sent := f.Sent()
+	for i := len(sent) - 1; i >= 0; i-- {
+		if match(sent[i]) {
+			return sent[i], true
+		}
+	}
+	return mailer.Message{}, false
+}
+
+// recipients lists the recipients of the messages sent, for the failures
+func (f *Fake) recipients() string {
+	var emails []string
+	for _, msg := range f.Sent() {
+		for _, list := range [][]mailer.Address{msg.To, msg.Cc, msg.Bcc} {
+			for _, a := range list {
+				emails = append(emails, a.Email)
+			}
+		}
+	}
+	if len(emails) == 0 {
+		return "no recipient"
+	}
+	return strings.Join(emails, ", ")
+}
+
+// attachmentName is the name of an attachment, the name of its file by default
+func attachmentName(a mailer.Attachment) string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return filepath.Base(a.Path)
+}
@@ -0,0 +1,65 @@
+package mailertest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/PrinMeshia/medego/mailer"
+)
+
+// recorder records the failures of the assertions instead of failing the test
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestFake(t *testing.T) {
+	fake := &Fake{}
+	var sender mailer.Sender = fake
+
+	if err := sender.Send(mailer.Message{
+		To:          mailer.Addresses("you@there.com"),
+		Bcc:         mailer.Addresses("audit@here.com"),
+		Template:    "password-reset",
+		Attachments: []mailer.Attachment{mailer.AttachFile("./files/invoice.pdf")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.AssertCount(t, 1)
+	if msg := fake.AssertSentTo(t, "You@There.com"); msg.Template != "password-reset" {
+		t.Errorf("expected the message sent, got %+v", msg)
+	}
+	fake.AssertSentTo(t, "audit@here.com")
+	fake.AssertTemplate(t, "password-reset")
+	fake.AssertAttachment(t, "invoice.pdf")
+
+	r := &recorder{TB: t}
+	fake.AssertSentTo(r, "someone@else.com")
+	fake.AssertTemplate(r, "welcome")
+	fake.AssertAttachment(r, "report.pdf")
+	fake.AssertNothingSent(r)
+	if len(r.failures) != 4 {
+		t.Errorf("expected the assertions to fail, got %v", r.failures)
+	}
+
+	fake.Reset()
+	fake.AssertNothingSent(t)
+}
+
+func TestFake_Err(t *testing.T) {
+	failure := errors.New("mail server unavailable")
+	fake := &Fake{Err: failure}
+
+	if err := fake.Send(mailer.Message{To: mailer.Addresses("you@there.com")}); !errors.Is(err, failure) {
+		t.Errorf("expected the error of the fake, got %v", err)
+	}
+	fake.AssertNothingSent(t)
+}
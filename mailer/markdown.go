@@ -0,0 +1,300 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// markdownLayout is the template of the mail folder laying out the HTML of the Markdown templates,
+// defaultLayout when missing
+const markdownLayout = "layout.tmpl"
+
+// markdown converts the .md.tmpl templates, with the GitHub extensions: tables, strikethrough,
+// task lists and links from bare URLs. Raw HTML is left out.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// Markdown is trusted Markdown, written as is in the .md.tmpl templates, whereas the other values,
+// translations included, are escaped so that they show as text. The type is lost when a queued message is stored in a
+// database, redis or badger.
+type Markdown string
+
+// escapeFunc is the name of escapeMarkdown in the Markdown templates
+const escapeFunc = "_escapeMarkdown"
+
+// escapeMarkdown writes a value of a Markdown template as text, escaping the ASCII punctuation
+// with backslashes, which the Markdown parser removes, and & as an entity so that it starts none.
+// Escapes are not removed in code spans. Missing values are written as empty strings.
+func escapeMarkdown(value interface{}) string {
+	switch v := value.(type) {
+	case Markdown:
+		return string(v)
+	case nil:
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, r := range fmt.Sprint(value) {
+		switch {
+		case r == '&':
+			sb.WriteString("&amp;")
+			continue
+		case r < utf8.RuneSelf && util.IsPunct(byte(r)):
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// escapeActions adds escapeMarkdown to the pipeline of the actions printing a value
+func escapeActions(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeActions(tree, child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) == 0 {
+			escape := parse.NewIdentifier(escapeFunc).SetTree(tree).SetPos(n.Pos)
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{escape}})
+		}
+	case *parse.IfNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	case *parse.RangeNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	case *parse.WithNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	}
+}
+
+// layoutData is given to the layout: the fields of the message, e.g. {{.Subject}}, and the HTML of
+// its Markdown, {{.Content}}
+type layoutData struct {
+	Message
+	Content template.HTML
+}
+
+// buildMarkdown creates the HTML and plain text versions of a message from its Markdown template:
+// the HTML in the layout, with its CSS inlined, and the plain text from the Markdown itself
+func (m *Mail) buildMarkdown(templatePath string, msg Message) (string, string, error) {
+	content, err := fs.ReadFile(m.templates(), templatePath)
+	if err != nil {
+		return "", "", err
+	}
+
+	t, err := texttemplate.New("email-template").Funcs(m.templateFuncs(msg)).
+		Funcs(texttemplate.FuncMap{escapeFunc: escapeMarkdown}).Parse(string(content))
+	if err != nil {
+		return "", "", err
+	}
+	// the data is escaped, as html/template does, so that it cannot add links, images or HTML
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			escapeActions(tmpl.Tree, tmpl.Tree.Root)
+		}
+	}
+
+	var source bytes.Buffer
+	if t.Lookup("body") != nil {
+		err = t.ExecuteTemplate(&source, "body", msg.Data)
+	} else {
+		err = t.Execute(&source, msg.Data)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	var body bytes.Buffer
+	if err := markdown.Convert(source.Bytes(), &body); err != nil {
+		return "", "", err
+	}
+
+	layout, err := fs.ReadFile(m.templates(), markdownLayout)
+	if errors.Is(err, fs.ErrNotExist) {
+		layout, err = []byte(defaultLayout), nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	lt, err := template.New(markdownLayout).Funcs(m.templateFuncs(msg)).Parse(string(layout))
+	if err != nil {
+		return "", "", err
+	}
+
+	var html bytes.Buffer
+	if err := lt.Execute(&html, layoutData{Message: msg, Content: template.HTML(body.String())}); err != nil {
+		return "", "", err
+	}
+
+	formattedMessage, err := m.inlineCSS(html.String())
+	if err != nil {
+		return "", "", err
+	}
+
+	return formattedMessage, markdownText(source.Bytes()), nil
+}
+
+// markdownText returns the plain text of Markdown: the text of its blocks, the items of its lists,
+// the URLs of its links after their text, and the alt text of its images
+func markdownText(source []byte) string {
+	var sb strings.Builder
+	lists := 0
+
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n := n.(type) {
+		case *ast.Text:
+			if entering {
+				value := n.Value(source)
+				if !n.IsRaw() {
+					value = util.ResolveEntityNames(util.ResolveNumericReferences(util.UnescapePunctuations(value)))
+				}
+				sb.Write(value)
+				if n.SoftLineBreak() || n.HardLineBreak() {
+					sb.WriteString("\n")
+				}
+			}
+		case *ast.String:
+			if entering {
+				sb.Write(n.Value)
+			}
+		case *ast.AutoLink:
+			if entering {
+				sb.Write(n.URL(source))
+			}
+		case *ast.Link:
+			if !entering {
+				fmt.Fprintf(&sb, " (%s)", util.ResolveEntityNames(util.ResolveNumericReferences(util.UnescapePunctuations(n.Destination))))
+			}
+		case *ast.RawHTML, *ast.HTMLBlock:
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeBlock, *ast.FencedCodeBlock:
+			if entering {
+				for i := 0; i < n.Lines().Len(); i++ {
+					line := n.Lines().At(i)
+					sb.Write(line.Value(source))
+				}
+				sb.WriteString("\n\n")
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.ThematicBreak:
+			if entering {
+				sb.WriteString("---\n\n")
+			}
+		case *ast.List:
+			if entering {
+				lists++
+			} else if lists--; lists == 0 {
+				sb.WriteString("\n")
+			}
+		case *ast.ListItem:
+			if entering {
+				sb.WriteString(strings.Repeat("   ", lists-1))
+				if list := n.Parent().(*ast.List); list.IsOrdered() {
+					index := list.Start
+					for s := n.PreviousSibling(); s != nil; s = s.PreviousSibling() {
+						index++
+					}
+					fmt.Fprintf(&sb, "%d. ", index)
+				} else {
+					sb.WriteString("- ")
+				}
+			}
+		case *ast.TextBlock:
+			if !entering {
+				sb.WriteString("\n")
+			}
+		case *ast.Paragraph, *ast.Heading, *east.Table:
+			if !entering {
+				sb.WriteString("\n\n")
+			}
+		case *east.TableHeader, *east.TableRow:
+			if !entering {
+				sb.WriteString("\n")
+			}
+		case *east.TableCell:
+			if !entering && n.NextSibling() != nil {
+				sb.WriteString(" | ")
+			}
+		case *east.TaskCheckBox:
+			if entering && n.IsChecked {
+				sb.WriteString("[x] ")
+			} else if entering {
+				sb.WriteString("[ ] ")
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	plain := strings.TrimSpace(sb.String())
+	for strings.Contains(plain, "\n\n\n") {
+		plain = strings.ReplaceAll(plain, "\n\n\n", "\n\n")
+	}
+	return plain + "\n"
+}
+
+// defaultLayout lays out the HTML of the Markdown templates in a column of 600 pixels at most,
+// the width of the screen on phones
+const defaultLayout = `<!doctype html>
+<html lang="{{with .Locale}}{{.}}{{else}}en{{end}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
+<title>{{.Subject}}</title>
+<style>
+body { margin: 0; padding: 0; background-color: #f4f5f7; }
+.wrapper { width: 100%; background-color: #f4f5f7; }
+.container { max-width: 600px; margin: 0 auto; padding: 24px 0; }
+.content { background-color: #ffffff; border-radius: 6px; padding: 32px; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; font-size: 16px; line-height: 1.5; color: #1f2328; }
+h1, h2, h3 { margin: 0 0 16px; line-height: 1.25; }
+p, ul, ol, pre, blockquote, table { margin: 0 0 16px; }
+a { color: #0969da; }
+code, pre { font-family: Menlo, Consolas, monospace; font-size: 14px; background-color: #f6f8fa; }
+pre { padding: 12px; overflow: auto; }
+blockquote { padding-left: 12px; border-left: 4px solid #d0d7de; color: #656d76; }
+table { border-collapse: collapse; }
+th, td { padding: 6px 12px; border: 1px solid #d0d7de; }
+img { max-width: 100%; }
+@media only screen and (max-width: 620px) {
+  .container { padding: 0 !important; }
+  .content { border-radius: 0 !important; padding: 20px !important; }
+}
+</style>
+</head>
+<body>
+<table class="wrapper" role="presentation" width="100%" cellpadding="0" cellspacing="0">
+<tr>
+<td>
+<div class="container">
+<div class="content">
+{{.Content}}
+</div>
+</div>
+</td>
+</tr>
+</table>
+</body>
+</html>
+`
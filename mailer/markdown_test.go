@@ -0,0 +1,146 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMail_buildMessages_InlineCSS(t *testing.T) {
+	m := &Mail{Templates: "./testdata/mail"}
+
+	html, _, err := m.buildMessages(Message{Template: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, `<p style="color:#333333">`) {
+		t.Errorf("expected the CSS of the HTML template to be inlined, got %s", html)
+	}
+}
+
+func TestMail_buildMessages_Markdown(t *testing.T) {
+	m := &Mail{Templates: "./testdata/mail"}
+	msg := Message{
+		Subject:  "Your order",
+		Template: "notice",
+		Data:     map[string]string{"Name": "Joe", "Link": "https://example.com/track?id=1&key=2"},
+	}
+
+	html, plain, err := m.buildMessages(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"<title>Your order</title>",
+		`<h1 style="`,
+		`<a href="https://example.com/track?id=1&amp;key=2" style="color:#0969da">Track it</a>`,
+		`<a href="https://example.com/help"`,
+		"@media only screen and (max-width: 620px)",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected the HTML to contain %s, got %s", expected, html)
+		}
+	}
+	if strings.Contains(html, "<script>") {
+		t.Errorf("expected the raw HTML to be left out, got %s", html)
+	}
+
+	expected := `Hello Joe
+
+Your order is on its way & will arrive soon:
+
+- Track it (https://example.com/track?id=1&key=2)
+- Reply to this message for help
+   1. or call us
+   2. or see https://example.com/help
+
+Thanks,
+The team
+`
+	if plain != expected {
+		t.Errorf("expected the plain text\n%s\ngot\n%s", expected, plain)
+	}
+}
+
+func TestMail_buildMessages_MarkdownEscape(t *testing.T) {
+	m := &Mail{FS: fstest.MapFS{
+		"hostile.md.tmpl": {Data: []byte(`{{define "body"}}# Hello {{.Name}}
+
+{{if .Note}}{{.Note}}{{end}}{{.Missing}}
+
+- [Track it]({{.Link}})
+{{end}}`)},
+	}}
+
+	hostile := `[click](https://evil.example) <img src=x onerror=alert(1)> **bold** https://evil.example &amp;`
+	html, plain, err := m.buildMessages(Message{Template: "hostile", Data: map[string]interface{}{
+		"Name": hostile,
+		"Note": Markdown("**trusted**"),
+		"Link": "https://example.com/track?id=1&key=(2)",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the data shows as text
+	for _, unexpected := range []string{`href="https://evil.example"`, "<img", "<strong>bold", "<no value>"} {
+		if strings.Contains(html, unexpected) {
+			t.Errorf("expected no %s, got %s", unexpected, html)
+		}
+	}
+	for _, expected := range []string{
+		"[click](https://evil.example) &lt;img src=x onerror=alert(1)&gt; **bold** https://evil.example &amp;amp;",
+		"<strong>trusted</strong>",
+		`<a href="https://example.com/track?id=1&amp;key=(2)"`,
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected the HTML to contain %s, got %s", expected, html)
+		}
+	}
+	if expected := "Hello " + hostile + "\n\ntrusted\n\n- Track it (https://example.com/track?id=1&key=(2))\n"; plain != expected {
+		t.Errorf("expected the plain text %q, got %q", expected, plain)
+	}
+}
+
+func TestMail_buildMessages_MarkdownLayout(t *testing.T) {
+	m := &Mail{FS: fstest.MapFS{
+		"welcome.md.tmpl": {Data: []byte("Welcome to **{{.}}**")},
+		"layout.tmpl":     {Data: []byte(`<html><body><h6>{{.Subject}}</h6>{{.Content}}</body></html>`)},
+	}}
+
+	html, plain, err := m.buildMessages(Message{Subject: "Hi", Template: "welcome", Data: "Medego"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, "<h6>Hi</h6><p>Welcome to <strong>Medego</strong></p>") {
+		t.Errorf("expected the content in the layout of the mail folder, got %s", html)
+	}
+	if plain != "Welcome to Medego\n" {
+		t.Errorf("expected the plain text, got %q", plain)
+	}
+}
+
+func TestMarkdownText(t *testing.T) {
+	var tests = []struct {
+		name     string
+		markdown string
+		expected string
+	}{
+		{"emphasis", "Some *emphasis* and `code`", "Some emphasis and code\n"},
+		{"escapes", `1\. not a list &copy;`, "1. not a list ©\n"},
+		{"heading", "## Title\nText", "Title\n\nText\n"},
+		{"image", "![the logo](logo.png)", "the logo\n"},
+		{"code block", "```\nfunc main() {}\n```", "func main() {}\n"},
+		{"ordered list", "3. three\n4. four", "3. three\n4. four\n"},
+		{"task list", "- [x] done\n- [ ] todo", "- [x] done\n- [ ] todo\n"},
+		{"table", "| a | b |\n|---|---|\n| 1 | 2 |", "a | b\n1 | 2\n"},
+		{"thematic break", "one\n\n---\n\ntwo", "one\n\n---\n\ntwo\n"},
+	}
+
+	for _, e := range tests {
+		if plain := markdownText([]byte(e.markdown)); plain != e.expected {
+			t.Errorf("%s: expected %q, got %q", e.name, e.expected, plain)
+		}
+	}
+}
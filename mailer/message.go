@@ -0,0 +1,105 @@
+package mailer
+
+import (
+	"errors"
+	"io"
+	"mime"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+)
+
+// errNoRecipient is returned for messages without To, Cc or Bcc
+var errNoRecipient = errors.New("mailer: message without recipient")
+
+// String returns the address as in a header, e.g. "Joe" <joe@example.com>
+func (a Address) String() string {
+	if a.Name == "" {
+		return a.Email
+	}
+	return (&netmail.Address{Name: a.Name, Address: a.Email}).String()
+}
+
+// Addresses returns the addresses of emails, without display names
+func Addresses(emails ...string) []Address {
+	addresses := make([]Address, len(emails))
+	for n, email := range emails {
+		addresses[n] = Address{Email: email}
+	}
+	return addresses
+}
+
+// AttachFile returns an attachment read from a file when the message is sent
+func AttachFile(path string) Attachment {
+	return Attachment{Name: filepath.Base(path), Path: path}
+}
+
+// AttachReader returns an attachment with the content of r
+func AttachReader(name, contentType string, r io.Reader) (Attachment, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return Attachment{Name: name, Data: data, ContentType: contentType}, nil
+}
+
+// load returns the attachment with its data, name and content type set
+func (a Attachment) load() (Attachment, error) {
+	if a.Name == "" {
+		a.Name = filepath.Base(a.Path)
+	}
+	if len(a.Data) == 0 && a.Path != "" {
+		data, err := os.ReadFile(a.Path)
+		if err != nil {
+			return a, err
+		}
+		a.Data = data
+	}
+	if a.ContentType == "" {
+		a.ContentType = mime.TypeByExtension(filepath.Ext(a.Name))
+	}
+	if a.ContentType == "" {
+		a.ContentType = "application/octet-stream"
+	}
+	return a, nil
+}
+
+// prepare returns the message with the default sender of the mailer, and its attachments loaded;
+// its errors are permanent
+func (m *Mail) prepare(msg Message) (Message, error) {
+	if msg.From == "" {
+		msg.From = m.FromAddress
+	}
+	if msg.FromName == "" {
+		msg.FromName = m.FromName
+	}
+
+	if len(msg.To)+len(msg.Cc)+len(msg.Bcc) == 0 {
+		return msg, Permanent(errNoRecipient)
+	}
+
+	attachments := make([]Attachment, len(msg.Attachments))
+	for n, a := range msg.Attachments {
+		loaded, err := a.load()
+		if err != nil {
+			return msg, Permanent(err)
+		}
+		attachments[n] = loaded
+	}
+	msg.Attachments = attachments
+	return msg, nil
+}
+
+// from returns the sender of a prepared message
+func (msg Message) from() Address {
+	return Address{Name: msg.FromName, Email: msg.From}
+}
+
+// addressStrings returns the addresses as in headers
+func addressStrings(addresses []Address) []string {
+	s := make([]string, len(addresses))
+	for n, a := range addresses {
+		s[n] = a.String()
+	}
+	return s
+}
@@ -0,0 +1,211 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Preview serves, in development, the messages written by the file driver, and previews of the
+// templates with sample data:
+//
+//	/                         lists the messages and the templates
+//	/messages/<id>.html       the HTML part of a message, .txt its plain text, .eml the MIME message
+//	/templates/<name>         the HTML of <name>.html.tmpl or <name>.md.tmpl, ?format=plain for its plain text
+//
+// The sample data of a template is read from <name>.sample.json in the mail folder, and the query
+// parameters of the preview are added to it, e.g. /templates/password-reset?Link=https://example.com;
+// ?locale=fr previews the template of a locale.
+type Preview struct {
+	Mail *Mail
+}
+
+// captureTypes are the content types of the files written by the file driver
+var captureTypes = map[string]string{
+	".html": "text/html; charset=utf-8",
+	".txt":  "text/plain; charset=utf-8",
+	".eml":  "message/rfc822",
+}
+
+func (p *Preview) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch name := strings.TrimPrefix(r.URL.Path, "/"); {
+	case name == "":
+		p.index(w)
+	case strings.HasPrefix(name, "messages/"):
+		p.message(w, strings.TrimPrefix(name, "messages/"))
+	case strings.HasPrefix(name, "templates/"):
+		p.template(w, r, strings.TrimPrefix(name, "templates/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// index lists the captured messages and the templates
+func (p *Preview) index(w http.ResponseWriter) {
+	captured, err := p.Mail.Captured()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var templates []string
+	seen := make(map[string]bool)
+	for _, ext := range []string{".html.tmpl", ".md.tmpl"} {
+		paths, err := fs.Glob(p.Mail.templates(), "*"+ext)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, path := range paths {
+			if name := strings.TrimSuffix(path, ext); !seen[name] {
+				seen[name] = true
+				templates = append(templates, name)
+			}
+		}
+	}
+	sort.Strings(templates)
+
+	buf := new(bytes.Buffer)
+	if err := previewTemplate.Execute(buf, struct {
+		Dir       string
+		Messages  []Captured
+		Templates []string
+	}{p.Mail.captureDir(), captured, templates}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
+
+// message serves a file written by the file driver
+func (p *Preview) message(w http.ResponseWriter, name string) {
+	contentType, ok := captureTypes[path.Ext(name)]
+	if !ok || name != path.Base(name) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(p.Mail.captureDir(), name))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if path.Ext(name) == ".eml" {
+		// opens in the mail client
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	}
+	_, _ = w.Write(content)
+}
+
+// template renders a template with its sample data
+func (p *Preview) template(w http.ResponseWriter, r *http.Request, name string) {
+	if name == "" || name != path.Base(name) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	data := make(map[string]interface{})
+	if sample, err := fs.ReadFile(p.Mail.templates(), name+".sample.json"); err == nil {
+		if err := json.Unmarshal(sample, &data); err != nil {
+			http.Error(w, name+".sample.json: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	query := r.URL.Query()
+	for key := range query {
+		if key != "format" && key != "locale" {
+			data[key] = query.Get(key)
+		}
+	}
+
+	msg := Message{Template: name, Subject: name, Locale: query.Get("locale"), Data: data}
+	content, plain, err := p.Mail.buildMessages(msg)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := "text/html; charset=utf-8"
+	if query.Get("format") == "plain" {
+		content, contentType = plain, "text/plain; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(content))
+}
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Mail</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; color: #1f2328; }
+header { background: #0969da; color: #fff; padding: 1.5rem 2rem; }
+header h1 { margin: 0; font-size: 1.25rem; }
+section { padding: 1rem 2rem; }
+h2 { font-size: 1rem; }
+table { border-collapse: collapse; width: 100%; font-size: .9rem; }
+th, td { text-align: left; padding: .4rem .6rem; border-bottom: 1px solid #d0d7de; }
+.empty { color: #656d76; }
+</style>
+</head>
+<body>
+<header>
+<h1>Mail</h1>
+</header>
+<section>
+<h2>Messages in {{.Dir}}</h2>
+{{if .Messages}}
+<table>
+<tr><th>Date</th><th>From</th><th>To</th><th>Subject</th><th></th></tr>
+{{range .Messages}}
+<tr>
+<td>{{.Date.Format "2006-01-02 15:04:05"}}</td>
+<td>{{.From}}</td>
+<td>{{.To}}</td>
+<td><a href="messages/{{.ID}}.html">{{.Subject}}</a></td>
+<td><a href="messages/{{.ID}}.txt">text</a> <a href="messages/{{.ID}}.eml">eml</a></td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p class="empty">No message yet: set MAILER_API=file to write the messages sent to {{.Dir}}.</p>
+{{end}}
+</section>
+<section>
+<h2>Templates</h2>
+{{if .Templates}}
+<table>
+{{range .Templates}}
+<tr><td><a href="templates/{{.}}">{{.}}</a></td><td><a href="templates/{{.}}?format=plain">text</a></td></tr>
+{{end}}
+</table>
+{{else}}
+<p class="empty">No template in the mail folder.</p>
+{{end}}
+</section>
+</body>
+</html>
+`))
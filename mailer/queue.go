@@ -0,0 +1,356 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkers     = 2
+	defaultMaxAttempts = 5
+	defaultBackoff     = 30 * time.Second
+	maxBackoff         = time.Hour
+	// sendLease is the time a worker has to send a job before another worker may claim it again,
+	// e.g. when the instance sending it crashed
+	sendLease    = 5 * time.Minute
+	pollInterval = time.Second
+)
+
+// Job is a message in the queue
+type Job struct {
+	ID        string    `json:"id"`
+	Message   Message   `json:"message"`
+	Attempts  int       `json:"attempts"`
+	NotBefore time.Time `json:"not_before"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// QueueStore keeps the jobs of the queue. A claimed job stays in the store until it is acked,
+// retried or buried, so that jobs survive a restart, and the jobs of a crashed worker are sent
+// again once their lease expires.
+type QueueStore interface {
+	// Push adds a job, ready from its NotBefore time
+	Push(job Job) error
+	// Pop claims the next job ready at now for the lease, or returns nil when none is ready
+	Pop(now time.Time, lease time.Duration) (*Job, error)
+	// Ack removes a job once sent
+	Ack(id string) error
+	// Retry saves a job that failed, ready again from its NotBefore time, unless it was cancelled
+	Retry(job Job) error
+	// Bury moves a job that failed for good to the dead letters
+	Bury(job Job) error
+	// DeadLetters returns the jobs that failed for good
+	DeadLetters() ([]Job, error)
+	// Requeue moves a dead letter back to the queue, ready at once
+	Requeue(id string) error
+	// Cancel removes a job from the queue, or returns ErrJobNotFound when it is not in the queue
+	Cancel(id string) error
+}
+
+// ErrJobNotFound is returned when a job is not in the store
+var ErrJobNotFound = errors.New("mailer: job not found")
+
+// permanentError is an error retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as permanent: the message is buried without being retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent tells whether sending again would fail the same way: the errors marked Permanent,
+// such as template errors, and the 5xx replies of SMTP servers. Other errors, such as network
+// errors and 4xx replies, are transient.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return true
+	}
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code >= 500
+}
+
+// queue runs the workers sending the jobs of the store
+type queue struct {
+	mu        sync.Mutex
+	started   bool
+	wake      chan struct{}
+	quit      chan struct{}
+	wg        sync.WaitGroup
+	callbacks map[string]func(Result)
+	limiter   *rateLimiter
+}
+
+// Enqueue adds a message to the queue, and returns the ID of its job. onResult is called once the
+// message is sent, or buried after its last attempt; as callbacks are not stored, it is only called
+// by the instance which queued the message, when it is still running.
+func (m *Mail) Enqueue(msg Message, onResult ...func(Result)) (string, error) {
+	return m.SendAt(msg, time.Now(), onResult...)
+}
+
+// SendAt queues a message to be sent at a time, and returns the ID of its job, to cancel it. The
+// store keeps the message until then: with a database, redis or badger store, it is still sent
+// after a restart.
+func (m *Mail) SendAt(msg Message, at time.Time, onResult ...func(Result)) (string, error) {
+	job := Job{ID: newJobID(), Message: msg, NotBefore: at, CreatedAt: time.Now()}
+
+	q := m.queue()
+	if len(onResult) > 0 {
+		q.mu.Lock()
+		q.callbacks[job.ID] = onResult[0]
+		q.mu.Unlock()
+	}
+
+	if err := m.store().Push(job); err != nil {
+		q.mu.Lock()
+		delete(q.callbacks, job.ID)
+		q.mu.Unlock()
+		return "", err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job.ID, nil
+}
+
+// SendAfter queues a message to be sent after a delay, and returns the ID of its job
+func (m *Mail) SendAfter(msg Message, delay time.Duration, onResult ...func(Result)) (string, error) {
+	return m.SendAt(msg, time.Now().Add(delay), onResult...)
+}
+
+// Cancel removes a queued message, e.g. a reminder which is no longer needed. It returns
+// ErrJobNotFound when the message is no longer in the queue, sent or buried; a message being sent
+// at the time may still go out.
+func (m *Mail) Cancel(id string) error {
+	if err := m.store().Cancel(id); err != nil {
+		return err
+	}
+
+	q := m.queue()
+	q.mu.Lock()
+	delete(q.callbacks, id)
+	q.mu.Unlock()
+	return nil
+}
+
+// Queued returns a Sender adding the messages to the queue: they are sent by the workers, retried
+// when they fail, and their final result goes to OnResult.
+func (m *Mail) Queued() Sender {
+	return queuedSender{m}
+}
+
+type queuedSender struct {
+	m *Mail
+}
+
+func (s queuedSender) Send(msg Message) error {
+	_, err := s.m.Enqueue(msg)
+	return err
+}
+
+// Start starts the workers sending the messages of the queue
+func (m *Mail) Start() {
+	q := m.queue()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return
+	}
+	q.started = true
+
+	workers := m.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	q.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go m.work(q)
+	}
+}
+
+// Stop stops the workers, once they have sent the messages in progress, and closes the connections
+// to the SMTP server
+func (m *Mail) Stop() {
+	q := m.queue()
+	q.mu.Lock()
+	if !q.started {
+		q.mu.Unlock()
+		return
+	}
+	q.started = false
+	close(q.quit)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+	m.closeSMTP()
+
+	q.mu.Lock()
+	q.quit = make(chan struct{})
+	q.mu.Unlock()
+}
+
+// DeadLetters returns the messages that could not be sent
+func (m *Mail) DeadLetters() ([]Job, error) {
+	return m.store().DeadLetters()
+}
+
+// Requeue sends a dead letter again
+func (m *Mail) Requeue(id string) error {
+	return m.store().Requeue(id)
+}
+
+// work sends the jobs of the store until the queue stops
+func (m *Mail) work(q *queue) {
+	defer q.wg.Done()
+
+	q.mu.Lock()
+	quit := q.quit
+	q.mu.Unlock()
+
+	for {
+		select {
+		case <-quit:
+			return
+		default:
+		}
+
+		if delay := q.limiter.reserve(time.Now()); delay > 0 {
+			select {
+			case <-quit:
+				q.limiter.cancel()
+				return
+			case <-time.After(delay):
+			}
+		}
+
+		job, err := m.store().Pop(time.Now(), sendLease)
+		if err != nil || job == nil {
+			q.limiter.cancel()
+			select {
+			case <-quit:
+				return
+			case <-q.wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		m.process(*job)
+	}
+}
+
+// process sends a job, then acks it, retries it later with exponential backoff, or buries it
+func (m *Mail) process(job Job) {
+	job.Attempts++
+	err := m.Send(job.Message)
+	if err == nil {
+		if err := m.store().Ack(job.ID); err != nil {
+			m.report(job, Result{Success: false, Error: err})
+			return
+		}
+		m.report(job, Result{Success: true})
+		return
+	}
+
+	job.LastError = err.Error()
+	maxAttempts := m.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	if IsPermanent(err) || job.Attempts >= maxAttempts {
+		if buryErr := m.store().Bury(job); buryErr != nil {
+			err = errors.Join(err, buryErr)
+		}
+		m.report(job, Result{Success: false, Error: err})
+		return
+	}
+
+	job.NotBefore = time.Now().Add(m.backoff(job.Attempts))
+	if retryErr := m.store().Retry(job); retryErr != nil {
+		m.report(job, Result{Success: false, Error: errors.Join(err, retryErr)})
+	}
+}
+
+// backoff returns the delay before the next attempt: Backoff doubled at each attempt, up to an hour
+func (m *Mail) backoff(attempts int) time.Duration {
+	base := m.Backoff
+	if base <= 0 {
+		base = defaultBackoff
+	}
+	delay := time.Duration(float64(base) * math.Pow(2, float64(attempts-1)))
+	if delay <= 0 || delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+// report sends the final result of a job to its callback, to OnResult and to the Results channel
+// when it has room
+func (m *Mail) report(job Job, res Result) {
+	res.JobID = job.ID
+	res.Attempts = job.Attempts
+
+	q := m.queue()
+	q.mu.Lock()
+	callback := q.callbacks[job.ID]
+	delete(q.callbacks, job.ID)
+	q.mu.Unlock()
+
+	if callback != nil {
+		callback(res)
+	}
+	if m.OnResult != nil {
+		m.OnResult(job, res)
+	}
+	if m.Results != nil {
+		select {
+		case m.Results <- res:
+		default:
+		}
+	}
+}
+
+func (m *Mail) queue() *queue {
+	m.init.Do(func() {
+		m.q = &queue{
+			wake:      make(chan struct{}, 1),
+			quit:      make(chan struct{}),
+			callbacks: make(map[string]func(Result)),
+		}
+		if m.Store == nil {
+			m.Store = NewMemoryStore()
+		}
+		if m.RateLimit > 0 {
+			m.q.limiter = &rateLimiter{rate: m.RateLimit}
+		}
+	})
+	return m.q
+}
+
+func (m *Mail) store() QueueStore {
+	m.queue()
+	return m.Store
+}
+
+func newJobID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,184 @@
+package mailer
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+const (
+	badgerJobPrefix  = "mailqueue:job:"
+	badgerDeadPrefix = "mailqueue:dead:"
+	// badgerRetries is the number of times a transaction is tried again when another one conflicts
+	badgerRetries = 10
+)
+
+// BadgerStore keeps the queue in badger, under the keys mailqueue:job:<id>, and the dead letters
+// under mailqueue:dead:<id>
+type BadgerStore struct {
+	Conn *badger.DB
+}
+
+// NewBadgerStore returns a store using db, e.g. the connection of the badger cache
+func NewBadgerStore(db *badger.DB) *BadgerStore {
+	return &BadgerStore{Conn: db}
+}
+
+// Push adds a job
+func (s *BadgerStore) Push(job Job) error {
+	return s.update(func(txn *badger.Txn) error {
+		return setJob(txn, badgerJobPrefix, job)
+	})
+}
+
+// Pop claims the ready job with the earliest NotBefore time
+func (s *BadgerStore) Pop(now time.Time, lease time.Duration) (*Job, error) {
+	var next *Job
+	err := s.update(func(txn *badger.Txn) error {
+		next = nil
+		jobs, err := jobsWithPrefix(txn, badgerJobPrefix)
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			if job.NotBefore.After(now) {
+				continue
+			}
+			if next == nil || job.NotBefore.Before(next.NotBefore) {
+				job := job
+				next = &job
+			}
+		}
+		if next == nil {
+			return nil
+		}
+
+		claimed := *next
+		claimed.NotBefore = now.Add(lease)
+		return setJob(txn, badgerJobPrefix, claimed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// Ack removes a job
+func (s *BadgerStore) Ack(id string) error {
+	return s.update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(badgerJobPrefix + id))
+	})
+}
+
+// Retry saves a job that failed, unless it was cancelled
+func (s *BadgerStore) Retry(job Job) error {
+	return s.update(func(txn *badger.Txn) error {
+		if _, err := txn.Get([]byte(badgerJobPrefix + job.ID)); errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return setJob(txn, badgerJobPrefix, job)
+	})
+}
+
+// Bury moves a job to the dead letters
+func (s *BadgerStore) Bury(job Job) error {
+	return s.update(func(txn *badger.Txn) error {
+		if err := txn.Delete([]byte(badgerJobPrefix + job.ID)); err != nil {
+			return err
+		}
+		return setJob(txn, badgerDeadPrefix, job)
+	})
+}
+
+// DeadLetters returns the buried jobs, oldest first
+func (s *BadgerStore) DeadLetters() ([]Job, error) {
+	var jobs []Job
+	err := s.Conn.View(func(txn *badger.Txn) error {
+		var err error
+		jobs, err = jobsWithPrefix(txn, badgerDeadPrefix)
+		return err
+	})
+	sortJobs(jobs)
+	return jobs, err
+}
+
+// Requeue moves a dead letter back to the queue
+func (s *BadgerStore) Requeue(id string) error {
+	return s.update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(badgerDeadPrefix + id))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return ErrJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var job Job
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &job)
+		}); err != nil {
+			return err
+		}
+		job.Attempts = 0
+		job.NotBefore = time.Now()
+
+		if err := txn.Delete([]byte(badgerDeadPrefix + id)); err != nil {
+			return err
+		}
+		return setJob(txn, badgerJobPrefix, job)
+	})
+}
+
+// Cancel removes a job from the queue
+func (s *BadgerStore) Cancel(id string) error {
+	return s.update(func(txn *badger.Txn) error {
+		if _, err := txn.Get([]byte(badgerJobPrefix + id)); errors.Is(err, badger.ErrKeyNotFound) {
+			return ErrJobNotFound
+		} else if err != nil {
+			return err
+		}
+		return txn.Delete([]byte(badgerJobPrefix + id))
+	})
+}
+
+// update runs fn in a transaction, again when it conflicts with the transaction of another worker
+func (s *BadgerStore) update(fn func(txn *badger.Txn) error) error {
+	var err error
+	for i := 0; i < badgerRetries; i++ {
+		if err = s.Conn.Update(fn); !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+	}
+	return err
+}
+
+func setJob(txn *badger.Txn, prefix string, job Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return txn.Set([]byte(prefix+job.ID), payload)
+}
+
+func jobsWithPrefix(txn *badger.Txn, prefix string) ([]Job, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = []byte(prefix)
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	var jobs []Job
+	for it.Rewind(); it.Valid(); it.Next() {
+		var job Job
+		if err := it.Item().Value(func(val []byte) error {
+			return json.Unmarshal(val, &job)
+		}); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,130 @@
+package mailer
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the queue in memory: the jobs are lost on restart
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+	dead map[string]Job
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs: make(map[string]Job),
+		dead: make(map[string]Job),
+	}
+}
+
+// Push adds a job
+func (s *MemoryStore) Push(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+// Pop claims the ready job with the earliest NotBefore time
+func (s *MemoryStore) Pop(now time.Time, lease time.Duration) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next *Job
+	for _, job := range s.jobs {
+		if job.NotBefore.After(now) {
+			continue
+		}
+		if next == nil || job.NotBefore.Before(next.NotBefore) {
+			job := job
+			next = &job
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	claimed := *next
+	claimed.NotBefore = now.Add(lease)
+	s.jobs[claimed.ID] = claimed
+	return next, nil
+}
+
+// Ack removes a job
+func (s *MemoryStore) Ack(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+// Retry saves a job that failed, unless it was cancelled
+func (s *MemoryStore) Retry(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[job.ID]; ok {
+		s.jobs[job.ID] = job
+	}
+	return nil
+}
+
+// Bury moves a job to the dead letters
+func (s *MemoryStore) Bury(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, job.ID)
+	s.dead[job.ID] = job
+	return nil
+}
+
+// DeadLetters returns the buried jobs, oldest first
+func (s *MemoryStore) DeadLetters() ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.dead))
+	for _, job := range s.dead {
+		jobs = append(jobs, job)
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// Requeue moves a dead letter back to the queue
+func (s *MemoryStore) Requeue(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.dead[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	delete(s.dead, id)
+	job.Attempts = 0
+	job.NotBefore = time.Now()
+	s.jobs[id] = job
+	return nil
+}
+
+// Cancel removes a job from the queue
+func (s *MemoryStore) Cancel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[id]; !ok {
+		return ErrJobNotFound
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+// sortJobs sorts jobs by creation time
+func sortJobs(jobs []Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+}
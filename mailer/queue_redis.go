@@ -0,0 +1,205 @@
+package mailer
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// popScript claims the first ready job of the sorted set, scored by NotBefore in milliseconds,
+// by moving its score to the end of the lease
+var popScript = redis.NewScript(2, `
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #ids == 0 then
+	return false
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ids[1])
+return redis.call('HGET', KEYS[2], ids[1])
+`)
+
+// retryScript saves a job, unless it was cancelled
+var retryScript = redis.NewScript(2, `
+if redis.call('HEXISTS', KEYS[2], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// RedisStore keeps the queue in redis: the jobs in the hash <prefix>:mailqueue:jobs, scheduled in
+// the sorted set <prefix>:mailqueue, and the dead letters in the hash <prefix>:mailqueue:dead
+type RedisStore struct {
+	Pool   *redis.Pool
+	Prefix string
+}
+
+// NewRedisStore returns a store using the connections of pool, e.g. the pool of the redis cache
+func NewRedisStore(pool *redis.Pool, prefix string) *RedisStore {
+	return &RedisStore{Pool: pool, Prefix: prefix}
+}
+
+// Push adds a job
+func (s *RedisStore) Push(job Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("HSET", s.key("jobs"), job.ID, payload)
+	_ = conn.Send("ZADD", s.key(""), job.NotBefore.UnixMilli(), job.ID)
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+// Pop claims the ready job with the earliest NotBefore time
+func (s *RedisStore) Pop(now time.Time, lease time.Duration) (*Job, error) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	payload, err := redis.Bytes(popScript.Do(conn, s.key(""), s.key("jobs"), now.UnixMilli(), now.Add(lease).UnixMilli()))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Ack removes a job
+func (s *RedisStore) Ack(id string) error {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("ZREM", s.key(""), id)
+	_ = conn.Send("HDEL", s.key("jobs"), id)
+	_, err := conn.Do("EXEC")
+	return err
+}
+
+// Retry saves a job that failed, unless it was cancelled
+func (s *RedisStore) Retry(job Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	_, err = retryScript.Do(conn, s.key(""), s.key("jobs"), job.ID, payload, job.NotBefore.UnixMilli())
+	return err
+}
+
+// Bury moves a job to the dead letters
+func (s *RedisStore) Bury(job Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("ZREM", s.key(""), job.ID)
+	_ = conn.Send("HDEL", s.key("jobs"), job.ID)
+	_ = conn.Send("HSET", s.key("dead"), job.ID, payload)
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+// DeadLetters returns the buried jobs, oldest first
+func (s *RedisStore) DeadLetters() ([]Job, error) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	payloads, err := redis.ByteSlices(conn.Do("HVALS", s.key("dead")))
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, 0, len(payloads))
+	for _, payload := range payloads {
+		var job Job
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// Requeue moves a dead letter back to the queue
+func (s *RedisStore) Requeue(id string) error {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	payload, err := redis.Bytes(conn.Do("HGET", s.key("dead"), id))
+	if err == redis.ErrNil {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var job Job
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	job.Attempts = 0
+	job.NotBefore = time.Now()
+	if payload, err = json.Marshal(job); err != nil {
+		return err
+	}
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("HDEL", s.key("dead"), id)
+	_ = conn.Send("HSET", s.key("jobs"), id, payload)
+	_ = conn.Send("ZADD", s.key(""), job.NotBefore.UnixMilli(), id)
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+// Cancel removes a job from the queue
+func (s *RedisStore) Cancel(id string) error {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("ZREM", s.key(""), id)
+	_ = conn.Send("HDEL", s.key("jobs"), id)
+	removed, err := redis.Ints(conn.Do("EXEC"))
+	if err != nil {
+		return err
+	}
+	if removed[1] == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// key returns the name of a key of the queue
+func (s *RedisStore) key(name string) string {
+	key := "mailqueue"
+	if s.Prefix != "" {
+		key = s.Prefix + ":" + key
+	}
+	if name != "" {
+		key += ":" + name
+	}
+	return key
+}
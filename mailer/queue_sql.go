@@ -0,0 +1,177 @@
+package mailer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLStore keeps the queue in the mail_queue table of a postgres, mysql or mariadb database,
+// created by `medego make mailqueue`
+type SQLStore struct {
+	DB    *sql.DB
+	Table string
+	// postgres takes $1 placeholders, mysql and mariadb ?
+	numbered bool
+}
+
+// NewSQLStore returns a store using the mail_queue table of db; databaseType is DATABASE_TYPE
+func NewSQLStore(db *sql.DB, databaseType string) *SQLStore {
+	t := strings.ToLower(databaseType)
+	return &SQLStore{
+		DB:       db,
+		Table:    "mail_queue",
+		numbered: t == "postgres" || t == "postgresql" || t == "pgx",
+	}
+}
+
+// Push adds a job
+func (s *SQLStore) Push(job Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec(s.query("insert into %s (id, payload, not_before, dead, created_at) values (?, ?, ?, false, ?)"),
+		job.ID, string(payload), job.NotBefore.UnixNano(), job.CreatedAt)
+	return err
+}
+
+// Pop claims the ready job with the earliest NotBefore time. The claim only succeeds when the
+// not_before of the job is still the one read, so that concurrent workers never claim the same job.
+func (s *SQLStore) Pop(now time.Time, lease time.Duration) (*Job, error) {
+	for {
+		var payload string
+		var notBefore int64
+		err := s.DB.QueryRow(s.query("select payload, not_before from %s where dead = false and not_before <= ? order by not_before limit 1"),
+			now.UnixNano()).Scan(&payload, &notBefore)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var job Job
+		if err := json.Unmarshal([]byte(payload), &job); err != nil {
+			return nil, err
+		}
+
+		res, err := s.DB.Exec(s.query("update %s set not_before = ? where id = ? and not_before = ?"),
+			now.Add(lease).UnixNano(), job.ID, notBefore)
+		if err != nil {
+			return nil, err
+		}
+		if claimed, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if claimed == 1 {
+			return &job, nil
+		}
+		// another worker claimed it first
+	}
+}
+
+// Ack removes a job
+func (s *SQLStore) Ack(id string) error {
+	_, err := s.DB.Exec(s.query("delete from %s where id = ?"), id)
+	return err
+}
+
+// Retry saves a job that failed, unless it was cancelled
+func (s *SQLStore) Retry(job Job) error {
+	return s.save(job, false)
+}
+
+// Bury moves a job to the dead letters
+func (s *SQLStore) Bury(job Job) error {
+	return s.save(job, true)
+}
+
+// DeadLetters returns the buried jobs, oldest first
+func (s *SQLStore) DeadLetters() ([]Job, error) {
+	rows, err := s.DB.Query(s.query("select payload from %s where dead = true order by created_at"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, err
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(payload), &job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// Requeue moves a dead letter back to the queue
+func (s *SQLStore) Requeue(id string) error {
+	var payload string
+	err := s.DB.QueryRow(s.query("select payload from %s where id = ? and dead = true"), id).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(payload), &job); err != nil {
+		return err
+	}
+	job.Attempts = 0
+	job.NotBefore = time.Now()
+	return s.save(job, false)
+}
+
+// Cancel removes a job from the queue
+func (s *SQLStore) Cancel(id string) error {
+	res, err := s.DB.Exec(s.query("delete from %s where id = ? and dead = false"), id)
+	if err != nil {
+		return err
+	}
+	if deleted, err := res.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) save(job Job, dead bool) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec(s.query("update %s set payload = ?, not_before = ?, dead = ? where id = ?"),
+		string(payload), job.NotBefore.UnixNano(), dead, job.ID)
+	return err
+}
+
+// query names the table, and numbers the placeholders for postgres
+func (s *SQLStore) query(q string) string {
+	q = fmt.Sprintf(q, s.Table)
+	if !s.numbered {
+		return q
+	}
+
+	var sb strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			sb.WriteString(fmt.Sprintf("$%d", n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
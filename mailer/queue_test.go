@@ -0,0 +1,295 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gomodule/redigo/redis"
+)
+
+func testStores(t *testing.T) map[string]QueueStore {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(t.TempDir()).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	return map[string]QueueStore{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(pool, "test"),
+		"badger": NewBadgerStore(db),
+	}
+}
+
+func TestQueueStore(t *testing.T) {
+	for name, store := range testStores(t) {
+		now := time.Now()
+		later := Job{ID: "later", Message: Message{To: Addresses("later@there.com")}, NotBefore: now.Add(10 * time.Minute), CreatedAt: now}
+		first := Job{ID: "first", Message: Message{To: Addresses("first@there.com"), Data: map[string]interface{}{"Link": "x"}}, NotBefore: now.Add(-time.Second), CreatedAt: now}
+		second := Job{ID: "second", Message: Message{To: Addresses("second@there.com")}, NotBefore: now, CreatedAt: now.Add(time.Millisecond)}
+		for _, job := range []Job{later, second, first} {
+			if err := store.Push(job); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+
+		job, err := store.Pop(now, time.Minute)
+		if err != nil || job == nil || job.ID != "first" {
+			t.Fatalf("%s: expected the earliest job, got %v, %v", name, job, err)
+		}
+		if len(job.Message.To) != 1 || job.Message.To[0].Email != "first@there.com" {
+			t.Errorf("%s: expected the message of the job, got %v", name, job.Message)
+		}
+
+		// the claimed job is leased, and the job for later not ready yet
+		job, _ = store.Pop(now.Add(time.Second), time.Minute)
+		if job == nil || job.ID != "second" {
+			t.Fatalf("%s: expected the second job, got %v", name, job)
+		}
+		if job, _ := store.Pop(now.Add(time.Second), time.Minute); job != nil {
+			t.Errorf("%s: expected no ready job, got %s", name, job.ID)
+		}
+		// once the lease expires, another worker claims the job again
+		if job, _ := store.Pop(now.Add(2*time.Minute), time.Minute); job == nil || job.ID != "first" {
+			t.Errorf("%s: expected the job to be claimed again after its lease, got %v", name, job)
+		}
+
+		if err := store.Ack("first"); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		second.Attempts = 1
+		second.LastError = "timeout"
+		second.NotBefore = now.Add(30 * time.Second)
+		if err := store.Retry(second); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		job, _ = store.Pop(now.Add(45*time.Second), time.Minute)
+		if job == nil || job.ID != "second" || job.Attempts != 1 || job.LastError != "timeout" {
+			t.Fatalf("%s: expected the retried job, got %v", name, job)
+		}
+
+		if err := store.Bury(*job); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		dead, err := store.DeadLetters()
+		if err != nil || len(dead) != 1 || dead[0].ID != "second" {
+			t.Errorf("%s: expected the buried job in the dead letters, got %v, %v", name, dead, err)
+		}
+		if job, _ := store.Pop(now.Add(time.Hour), time.Minute); job == nil || job.ID != "later" {
+			t.Errorf("%s: expected only the job for later to be left, got %v", name, job)
+		}
+
+		if err := store.Requeue("second"); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if dead, _ := store.DeadLetters(); len(dead) != 0 {
+			t.Errorf("%s: expected no dead letter after requeuing, got %d", name, len(dead))
+		}
+		if job, _ := store.Pop(time.Now(), time.Minute); job == nil || job.ID != "second" || job.Attempts != 0 {
+			t.Errorf("%s: expected the requeued job, got %v", name, job)
+		}
+		if err := store.Requeue("unknown"); !errors.Is(err, ErrJobNotFound) {
+			t.Errorf("%s: expected ErrJobNotFound, got %v", name, err)
+		}
+
+		// a cancelled job is not sent, even when it failed while being cancelled
+		if err := store.Cancel("second"); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if err := store.Retry(second); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if err := store.Cancel("later"); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if job, _ := store.Pop(now.Add(24*time.Hour), time.Minute); job != nil {
+			t.Errorf("%s: expected the cancelled jobs to be removed, got %s", name, job.ID)
+		}
+		if err := store.Cancel("later"); !errors.Is(err, ErrJobNotFound) {
+			t.Errorf("%s: expected ErrJobNotFound for a cancelled job, got %v", name, err)
+		}
+	}
+}
+
+func TestMail_Queue(t *testing.T) {
+	m := &Mail{
+		Templates:   "./testdata/mail",
+		Host:        "127.0.0.1",
+		Port:        1, // nothing listens: the attempts fail with transient errors
+		Encryption:  "none",
+		FromAddress: "me@here.com",
+		Workers:     2,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		Results:     make(chan Result, 2),
+	}
+	m.Start()
+	defer m.Stop()
+
+	results := make(chan Result, 2)
+	callback := func(res Result) { results <- res }
+
+	retried, err := m.Enqueue(Message{To: Addresses("you@there.com"), Subject: "test", Template: "test"}, callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid, err := m.Enqueue(Message{To: Addresses("you@there.com"), Subject: "test", Template: "missing"}, callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		select {
+		case res := <-results:
+			if res.Success || res.Error == nil {
+				t.Errorf("expected job %s to fail", res.JobID)
+			}
+			attempts[res.JobID] = res.Attempts
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for the results")
+		}
+	}
+
+	if attempts[retried] != 3 {
+		t.Errorf("expected the transient errors to be retried up to 3 attempts, got %d", attempts[retried])
+	}
+	if attempts[invalid] != 1 {
+		t.Errorf("expected the template error not to be retried, got %d attempts", attempts[invalid])
+	}
+	if len(m.Results) != 2 {
+		t.Errorf("expected the results on the Results channel too, got %d", len(m.Results))
+	}
+
+	dead, err := m.DeadLetters()
+	if err != nil || len(dead) != 2 {
+		t.Fatalf("expected both messages in the dead letters, got %d, %v", len(dead), err)
+	}
+	for _, job := range dead {
+		if job.LastError == "" {
+			t.Errorf("expected the last error of job %s", job.ID)
+		}
+	}
+}
+
+func TestMail_SendAt(t *testing.T) {
+	results := make(chan Result, 2)
+	m := &Mail{
+		Templates:   "./testdata/mail",
+		FromAddress: "me@here.com",
+		API:         "file",
+		Dir:         t.TempDir(),
+		OnResult:    func(job Job, res Result) { results <- res },
+	}
+	m.Start()
+	defer m.Stop()
+
+	msg := Message{To: Addresses("you@there.com"), Subject: "reminder", Template: "test"}
+	start := time.Now()
+	delayed, err := m.SendAfter(msg, 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, err := m.SendAt(msg, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Cancel(cancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case res := <-results:
+		if res.JobID != delayed || !res.Success {
+			t.Errorf("expected the delayed message to be sent, got %+v", res)
+		}
+		if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+			t.Errorf("expected the message to be sent after its delay, got %s", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the delayed message")
+	}
+
+	select {
+	case res := <-results:
+		t.Errorf("expected the cancelled message not to be sent, got %+v", res)
+	case <-time.After(2 * time.Second):
+	}
+
+	if err := m.Cancel(delayed); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound for a sent message, got %v", err)
+	}
+}
+
+func TestMail_Queued(t *testing.T) {
+	results := make(chan Result, 1)
+	m := &Mail{
+		Templates:   "./testdata/mail",
+		FromAddress: "me@here.com",
+		API:         "file",
+		Dir:         t.TempDir(),
+		OnResult:    func(job Job, res Result) { results <- res },
+	}
+	m.Start()
+	defer m.Stop()
+
+	var sender Sender = m.Queued()
+	if err := sender.Send(Message{To: Addresses("you@there.com"), Subject: "test", Template: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case res := <-results:
+		if !res.Success || res.JobID == "" {
+			t.Errorf("expected the queued message to be sent, got %+v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the queued message")
+	}
+	if captured, _ := m.Captured(); len(captured) != 1 {
+		t.Errorf("expected the message to be written by the file driver, got %d", len(captured))
+	}
+}
+
+func TestMail_backoff(t *testing.T) {
+	m := &Mail{Backoff: time.Second}
+	for attempts, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 20: time.Hour} {
+		if got := m.backoff(attempts); got != expected {
+			t.Errorf("attempt %d: expected %s, got %s", attempts, expected, got)
+		}
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	var tests = []struct {
+		err       error
+		permanent bool
+	}{
+		{errors.New("connection refused"), false},
+		{Permanent(errors.New("template not found")), true},
+		{fmt.Errorf("sending: %w", Permanent(errors.New("bad address"))), true},
+		{&textproto.Error{Code: 550, Msg: "mailbox unavailable"}, true},
+		{&textproto.Error{Code: 421, Msg: "service not available"}, false},
+	}
+
+	for _, e := range tests {
+		if got := IsPermanent(e.err); got != e.permanent {
+			t.Errorf("%s: expected permanent %v, got %v", e.err, e.permanent, got)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package mailer
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	mail "github.com/xhit/go-simple-mail/v2"
+)
+
+const (
+	smtpTimeout = 10 * time.Second
+	// smtpIdleCheck is the idle time after which a pooled connection is checked with a NOOP before
+	// it is used again
+	smtpIdleCheck = 15 * time.Second
+	// smtpMaxIdle is the idle time after which a pooled connection is closed, as servers drop them
+	smtpMaxIdle = 5 * time.Minute
+)
+
+// smtpConn is a connection to the SMTP server
+type smtpConn struct {
+	client *mail.SMTPClient
+	used   time.Time
+}
+
+func (c *smtpConn) close() {
+	_ = c.client.Quit()
+	_ = c.client.Close()
+}
+
+// smtpPool keeps the connections to the SMTP server open between messages, one per worker at most
+type smtpPool struct {
+	mu   sync.Mutex
+	idle []*smtpConn
+}
+
+// smtpConn returns an idle connection of the pool, or opens a new one when none is left or the
+// idle ones are broken
+func (m *Mail) smtpConn() (*smtpConn, error) {
+	p := &m.smtp
+	for {
+		p.mu.Lock()
+		n := len(p.idle)
+		if n == 0 {
+			p.mu.Unlock()
+			break
+		}
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+
+		idle := time.Since(c.used)
+		if idle < smtpMaxIdle && (idle < smtpIdleCheck || c.client.Noop() == nil) {
+			return c, nil
+		}
+		c.close()
+	}
+
+	server := mail.NewSMTPClient()
+	server.Host = m.Host
+	server.Port = m.Port
+	server.Username = m.Username
+	server.Password = m.Password
+	server.Encryption = m.getEncryption(m.Encryption)
+	server.KeepAlive = true
+	server.ConnectTimeout = smtpTimeout
+	server.SendTimeout = smtpTimeout
+
+	client, err := server.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return &smtpConn{client: client}, nil
+}
+
+// releaseSMTP returns a connection to the pool, or closes it when the pool is full
+func (m *Mail) releaseSMTP(c *smtpConn) {
+	size := m.Workers
+	if size <= 0 {
+		size = defaultWorkers
+	}
+
+	c.used = time.Now()
+	p := &m.smtp
+	p.mu.Lock()
+	if len(p.idle) < size {
+		p.idle = append(p.idle, c)
+		c = nil
+	}
+	p.mu.Unlock()
+
+	if c != nil {
+		c.close()
+	}
+}
+
+// closeSMTP closes the idle connections of the pool
+func (m *Mail) closeSMTP() {
+	p := &m.smtp
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, c := range idle {
+		c.close()
+	}
+}
+
+// rateLimiter lets the queue send rate messages a minute: a burst of rate messages, then one
+// every minute/rate
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   int
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes the turn of a message, and returns the delay before it may be sent
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.last.IsZero() {
+		l.tokens = float64(l.rate)
+	} else {
+		l.tokens = math.Min(float64(l.rate), l.tokens+now.Sub(l.last).Minutes()*float64(l.rate))
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / float64(l.rate) * float64(time.Minute))
+}
+
+// cancel gives back a turn which was not used
+func (l *rateLimiter) cancel() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.tokens = math.Min(float64(l.rate), l.tokens+1)
+	l.mu.Unlock()
+}
@@ -0,0 +1,228 @@
+package mailer
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpServer is a minimal SMTP server, recording the connections and the messages
+type smtpServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	messages []string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{listener: listener}
+	t.Cleanup(func() {
+		_ = listener.Close()
+		s.drop()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		command, _, _ := strings.Cut(strings.ToUpper(line), " ")
+		switch command {
+		case "EHLO":
+			_ = tp.PrintfLine("250-localhost")
+			_ = tp.PrintfLine("250 8BITMIME")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, string(data))
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 queued")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 ok")
+		}
+	}
+}
+
+// drop closes the connections, as a server restarting
+func (s *smtpServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+func (s *smtpServer) counts() (conns int, messages int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns), len(s.messages)
+}
+
+func TestMail_SendSMTPMessage_Pool(t *testing.T) {
+	server := newSMTPServer(t)
+	m := &Mail{
+		Templates:   "./testdata/mail",
+		Host:        "127.0.0.1",
+		Port:        server.listener.Addr().(*net.TCPAddr).Port,
+		Encryption:  "none",
+		FromAddress: "me@here.com",
+	}
+	defer m.closeSMTP()
+
+	msg := Message{To: Addresses("you@there.com"), Subject: "test", Template: "test"}
+	for i := 0; i < 3; i++ {
+		if err := m.SendSMTPMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if conns, messages := server.counts(); conns != 1 || messages != 3 {
+		t.Errorf("expected 3 messages on one connection, got %d messages on %d connections", messages, conns)
+	}
+
+	// the idle connection is broken: it is checked before use, and replaced
+	server.drop()
+	m.smtp.mu.Lock()
+	m.smtp.idle[0].used = time.Now().Add(-time.Minute)
+	m.smtp.mu.Unlock()
+
+	if err := m.SendSMTPMessage(msg); err != nil {
+		t.Fatal(err)
+	}
+	if conns, messages := server.counts(); conns != 2 || messages != 4 {
+		t.Errorf("expected a new connection for the 4th message, got %d messages on %d connections", messages, conns)
+	}
+}
+
+func TestMail_SendSMTPMessage_DKIM(t *testing.T) {
+	server := newSMTPServer(t)
+	m := &Mail{
+		Templates:   "./testdata/mail",
+		Host:        "127.0.0.1",
+		Port:        server.listener.Addr().(*net.TCPAddr).Port,
+		Encryption:  "none",
+		FromAddress: "me@here.com",
+		DKIM:        testDKIM(t),
+	}
+	defer m.closeSMTP()
+
+	if err := m.SendSMTPMessage(Message{To: Addresses("you@there.com"), Subject: "test", Template: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	sent := server.messages[0]
+	server.mu.Unlock()
+	verifyDKIM(t, m.DKIM, sent)
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{rate: 2}
+	now := time.Now()
+
+	var tests = []struct {
+		name     string
+		at       time.Time
+		expected time.Duration
+	}{
+		{"first of the burst", now, 0},
+		{"second of the burst", now, 0},
+		{"after the burst", now, 30 * time.Second},
+		{"once the turn passed", now.Add(30 * time.Second), 30 * time.Second},
+		{"once the bucket refilled", now.Add(5 * time.Minute), 0},
+	}
+
+	for _, e := range tests {
+		if delay := l.reserve(e.at); delay != e.expected {
+			t.Errorf("%s: expected a delay of %s, got %s", e.name, e.expected, delay)
+		}
+	}
+
+	// a turn given back is taken by the next message
+	l = &rateLimiter{rate: 1}
+	l.reserve(now)
+	l.cancel()
+	if delay := l.reserve(now); delay != 0 {
+		t.Errorf("expected the turn given back, got a delay of %s", delay)
+	}
+
+	var unlimited *rateLimiter
+	if delay := unlimited.reserve(now); delay != 0 {
+		t.Errorf("expected no delay without limit, got %s", delay)
+	}
+}
+
+func TestMail_Queue_RateLimit(t *testing.T) {
+	results := make(chan Result, 2)
+	m := &Mail{
+		Templates:   "./testdata/mail",
+		FromAddress: "me@here.com",
+		API:         "file",
+		Dir:         t.TempDir(),
+		Workers:     2,
+		RateLimit:   1,
+		OnResult:    func(job Job, res Result) { results <- res },
+	}
+	m.Start()
+
+	for i := 0; i < 2; i++ {
+		if err := m.Queued().Send(Message{To: Addresses("you@there.com"), Subject: "test", Template: "test"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	<-results
+	select {
+	case res := <-results:
+		t.Errorf("expected the second message to wait for its turn, got %+v", res)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// the workers waiting for their turn stop at once
+	stopped := make(chan struct{})
+	go func() {
+		m.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out stopping the workers")
+	}
+}
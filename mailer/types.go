@@ -1,33 +1,107 @@
 package mailer
 
+import (
+	"io/fs"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/PrinMeshia/medego/i18n"
+)
+
+// Sender sends messages. Apps depend on it rather than on Mail, so that tests can record the
+// messages with a mailertest.Fake.
+type Sender interface {
+	Send(msg Message) error
+}
+
 type Mail struct {
-	Domain      string
-	Templates   string
+	Domain    string
+	Templates string
+	// FS holds the templates, e.g. an embed.FS; when nil, they are read from the Templates folder
+	FS          fs.FS
 	Host        string
 	Port        int
 	Username    string
 	Password    string
-	Encryption   string
+	Encryption  string
 	FromAddress string
 	FromName    string
-	Jobs        chan Message
-	Results     chan Result
-	API         string
-	APIKey      string
-	APIUrl      string
+	// Jobs queues the messages sent on it; Results gets the final result of each message when it has room
+	Jobs       chan Message
+	Results    chan Result
+	API        string
+	APIKey     string
+	APIUrl     string
+	Translator i18n.Translator
+	// HTTPClient calls the APIs, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Dir is the folder of the messages written by the file driver, tmp/mail by default
+	Dir string
+	// Logger gets the messages of the log driver, log.Default() when nil
+	Logger *log.Logger
+	// DKIM signs the messages sent with SMTP or written by the file driver, when set
+	DKIM *DKIM
+
+	// Store keeps the queued messages, in memory when nil
+	Store QueueStore
+	// Workers is the number of messages sent at once, 2 by default
+	Workers int
+	// MaxAttempts is the number of attempts to send a message before it is buried, 5 by default
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled at each retry; 30 seconds by default
+	Backoff time.Duration
+	// OnResult is called with the final result of each queued message
+	OnResult func(job Job, res Result)
+	// RateLimit is the number of messages the queue sends a minute at most, unlimited when 0
+	RateLimit int
+
+	init sync.Once
+	q    *queue
+	smtp smtpPool
 }
 
 type Message struct {
-	From        string
-	FromName    string
-	To          string
-	Subject     string
-	Template   string
-	Attachments []string
-	Data        interface{}
+	// From and FromName default to the FromAddress and FromName of the mailer
+	From     string
+	FromName string
+	To       []Address
+	Cc       []Address
+	Bcc      []Address
+	ReplyTo  Address
+	Subject  string
+	Template string
+	// Attachments are attached, or shown in the HTML when inline
+	Attachments []Attachment
+	// Headers are added to the message, e.g. List-Unsubscribe
+	Headers map[string]string
+	// Data must encode to JSON when the queue is stored in a database, redis or badger
+	Data   interface{}
+	Locale string
+}
+
+// Address is an email address, with an optional display name
+type Address struct {
+	Name  string
+	Email string
+}
+
+// Attachment is a file attached to a message, read from Path when Data is empty. An inline
+// attachment is shown in the HTML by its name, e.g. <img src="cid:logo.png"> for logo.png.
+type Attachment struct {
+	Name string
+	Path string
+	Data []byte
+	// ContentType is guessed from the extension of the name when empty
+	ContentType string
+	Inline      bool
 }
 
 type Result struct {
 	Success bool
 	Error   error
+	// JobID and Attempts are set for the messages sent through the queue
+	JobID    string
+	Attempts int
 }
@@ -1,19 +1,31 @@
 package medego
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/PrinMeshia/medego/cache"
+	"github.com/PrinMeshia/medego/events"
+	"github.com/PrinMeshia/medego/i18n"
 	"github.com/PrinMeshia/medego/mailer"
+	"github.com/PrinMeshia/medego/pubsub"
 	"github.com/PrinMeshia/medego/render"
 	"github.com/PrinMeshia/medego/session"
+	"github.com/PrinMeshia/medego/sse"
+	"github.com/PrinMeshia/medego/static"
+	"github.com/PrinMeshia/medego/ws"
 	"github.com/dgraph-io/badger/v4"
 	"github.com/go-chi/chi/v5"
 	"github.com/gomodule/redigo/redis"
@@ -35,9 +47,12 @@ const (
 const (
 	defaultPort         = "8080"
 	defaultRenderer     = "html"
+	defaultLocale       = "en"
 	defaultIdleTimeout  = 30 * time.Second
 	defaultReadTimeout  = 30 * time.Second
 	defaultWriteTimeout = 600 * time.Second
+	// time given to the requests, and websocket connections, in progress to end on shutdown
+	defaultShutdownTimeout = 30 * time.Second
 )
 
 func (c *Medego) New(rootPath string) error {
@@ -45,7 +60,7 @@ func (c *Medego) New(rootPath string) error {
 		rootPath: rootPath,
 		folderNames: []string{
 			"src/middleware", "src/handlers", "src/data",
-			"migrations", "templates", "public", "mail",
+			"migrations", "seeds", "templates", "public", "mail", "lang",
 			"tmp/logs", "tmp/cache"},
 	}
 	if err := c.Init(pathConfig); err != nil {
@@ -75,6 +90,8 @@ func (c *Medego) New(rootPath string) error {
 		}
 	}
 
+	c.Events = events.Default
+
 	scheduler := cron.New()
 	c.Scheduler = scheduler
 
@@ -96,13 +113,43 @@ func (c *Medego) New(rootPath string) error {
 		}
 	}
 
+	if os.Getenv("PUBSUB") == "redis" {
+		if redisPool == nil {
+			redisPool = c.createRedisPool()
+		}
+		c.PubSub = pubsub.NewRedis(redisPool, os.Getenv("REDIS_PREFIX"))
+	} else {
+		c.PubSub = pubsub.NewMemory()
+	}
+	c.SSE = sse.NewBroker(c.PubSub)
+	c.WebSocket = ws.NewHub(c.PubSub)
+	c.WebSocket.Session = c.sessionUserID
+
 	c.InfoLog = infoLog
 	c.ErrorLog = errorLog
 
 	c.Debug, _ = strconv.ParseBool(os.Getenv("DEBUG"))
 	c.Version = version
 	c.RootPath = rootPath
-	c.Mail = c.createMailer()
+
+	c.I18n = i18n.New(defaultLocale)
+	if locale := os.Getenv("DEFAULT_LOCALE"); locale != "" {
+		c.I18n.DefaultLocale = i18n.Normalize(locale)
+	}
+	if err := c.I18n.LoadFS(c.Files("lang"), "."); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	mail, err := c.createMailer()
+	if err != nil {
+		return err
+	}
+	c.Mailer = mail
+	c.Mail = mail.Queued()
+
+	c.Assets = static.New(c.Files("public"))
+	c.Assets.Debug = c.Debug
+
 	c.Routes = c.routes().(*chi.Mux)
 
 	c.Config = ServerConfig{
@@ -169,14 +216,16 @@ func (c *Medego) New(rootPath string) error {
 		c.JetViews = views
 
 	} else {
-		var views = jet.NewSet(
-			jet.NewOSFileSystemLoader(fmt.Sprintf("%s/templates", rootPath)),
-		)
+		var loader jet.Loader = jet.NewOSFileSystemLoader(fmt.Sprintf("%s/templates", rootPath))
+		if templates := c.embedded("templates"); templates != nil {
+			loader = render.NewFSLoader(templates)
+		}
+		var views = jet.NewSet(loader)
 		c.JetViews = views
 	}
 
 	c.createRenderer()
-	go c.Mail.ListenForMail()
+	go c.Mailer.ListenForMail()
 	return nil
 }
 func (c *Medego) Init(p initPaths) error {
@@ -195,6 +244,14 @@ func (c *Medego) Init(p initPaths) error {
 }
 
 func (c *Medego) ListenAndServe() {
+	// the Go templates are parsed once the app has added its functions with Render.AddFunc,
+	// so that a broken template stops the server here rather than failing its first request
+	if strings.ToLower(c.Config.Renderer) == "go" && !c.Debug {
+		if err := c.Render.LoadTemplates(); err != nil {
+			c.ErrorLog.Fatal(err)
+		}
+	}
+
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%s", c.Config.Port),
 		ErrorLog:     c.ErrorLog,
@@ -211,12 +268,79 @@ func (c *Medego) ListenAndServe() {
 	if redisPool != nil {
 		defer redisPool.Close()
 	}
+	if c.PubSub != nil {
+		defer c.PubSub.Close()
+	}
 	if badgerConn != nil {
 		defer badgerConn.Close()
 	}
+	// the workers end the messages in progress before the stores close
+	defer c.Mailer.Stop()
+
+	// websocket connections are hijacked, so the server does not wait for them
+	srv.RegisterOnShutdown(func() {
+		if c.WebSocket == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+		defer cancel()
+		if err := c.WebSocket.Shutdown(ctx); err != nil {
+			c.ErrorLog.Println("closing websocket connections:", err)
+		}
+	})
+
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+
+		c.InfoLog.Println("Shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			c.ErrorLog.Println("shutting down:", err)
+		}
+	}()
 
 	c.InfoLog.Printf("Listening on port %s", c.Config.Port)
-	c.ErrorLog.Fatal(srv.ListenAndServe())
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		c.ErrorLog.Fatal(err)
+	}
+	<-shutdown
+}
+
+// sessionUserID returns the ID of the user logged in the session of a request, or 0 when the
+// session middleware has not loaded it
+func (c *Medego) sessionUserID(r *http.Request) int {
+	if !c.sessionLoaded(r) {
+		return 0
+	}
+	return c.Session.GetInt(r.Context(), "userID")
+}
+
+// Files returns a folder of the application, such as templates or migrations: the folder of FS
+// when the application embeds its files, or else the folder under RootPath
+func (c *Medego) Files(dir string) fs.FS {
+	if sub := c.embedded(dir); sub != nil {
+		return sub
+	}
+	return os.DirFS(filepath.Join(c.RootPath, dir))
+}
+
+// embedded returns a folder of FS, or nil when the files are read from disk: without FS, and
+// in debug mode so that edited files are reloaded
+func (c *Medego) embedded(dir string) fs.FS {
+	if c.FS == nil || c.Debug {
+		return nil
+	}
+	sub, err := fs.Sub(c.FS, dir)
+	if err != nil {
+		return nil
+	}
+	return sub
 }
 
 func (c *Medego) checkDotEnv(path string) error {
@@ -231,21 +355,29 @@ func (c *Medego) startLoggers() (*log.Logger, *log.Logger) {
 
 func (c *Medego) createRenderer() {
 	myRenderer := render.Render{
-		Renderer: c.Config.Renderer,
-		RootPath: c.RootPath,
-		Port:     c.Config.Port,
-		JetViews: c.JetViews,
-		Session:  c.Session,
+		Renderer:   c.Config.Renderer,
+		RootPath:   c.RootPath,
+		Secure:     c.Server.Secure,
+		Port:       c.Config.Port,
+		ServerName: c.Server.ServerName,
+		URL:        c.Server.URL,
+		Debug:      c.Debug,
+		JetViews:   c.JetViews,
+		FS:         c.embedded("templates"),
+		Session:    c.Session,
+		Translator: c.I18n,
+		Assets:     c.Assets,
 	}
 	c.Render = &myRenderer
 
 }
 
-func (c *Medego) createMailer() mailer.Mail {
+func (c *Medego) createMailer() (*mailer.Mail, error) {
 	port, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
-	m := mailer.Mail{
+	m := &mailer.Mail{
 		Domain:      os.Getenv("MAIL_DOMAIN"),
 		Templates:   c.RootPath + "/mail",
+		FS:          c.embedded("mail"),
 		Host:        os.Getenv("SMTP_HOST"),
 		Port:        port,
 		Username:    os.Getenv("SMTP_USERNAME"),
@@ -258,8 +390,58 @@ func (c *Medego) createMailer() mailer.Mail {
 		API:         os.Getenv("MAILER_API"),
 		APIKey:      os.Getenv("MAILER_KEY"),
 		APIUrl:      os.Getenv("MAILER_URL"),
+		Translator:  c.I18n,
+		Dir:         c.RootPath + "/tmp/mail",
+		Logger:      c.InfoLog,
+	}
+	m.OnResult = func(job mailer.Job, res mailer.Result) {
+		if res.Error != nil {
+			c.ErrorLog.Printf("mail %q could not be sent after %d attempts: %v", job.Message.Subject, res.Attempts, res.Error)
+		}
 	}
-	return m
+
+	m.Workers, _ = strconv.Atoi(os.Getenv("MAIL_WORKERS"))
+	m.MaxAttempts, _ = strconv.Atoi(os.Getenv("MAIL_MAX_ATTEMPTS"))
+	m.RateLimit, _ = strconv.Atoi(os.Getenv("MAIL_RATE_LIMIT"))
+
+	if key := os.Getenv("DKIM_PRIVATE_KEY"); key != "" {
+		// the PEM key itself, or the path of its file
+		privateKey := []byte(key)
+		if !strings.HasPrefix(strings.TrimSpace(key), "-----BEGIN") {
+			var err error
+			if privateKey, err = os.ReadFile(key); err != nil {
+				return nil, fmt.Errorf("DKIM private key: %w", err)
+			}
+		}
+		m.DKIM = &mailer.DKIM{
+			Domain:     os.Getenv("DKIM_DOMAIN"),
+			Selector:   os.Getenv("DKIM_SELECTOR"),
+			PrivateKey: privateKey,
+		}
+		if m.DKIM.Domain == "" {
+			m.DKIM.Domain = m.Domain
+		}
+	}
+
+	switch os.Getenv("MAIL_QUEUE") {
+	case "database":
+		// falling back to the memory store would lose the queued mails on restart
+		if c.DB.Pool == nil {
+			return nil, errors.New("MAIL_QUEUE=database needs a database, set DATABASE_TYPE")
+		}
+		m.Store = mailer.NewSQLStore(c.DB.Pool, c.DB.DataType)
+	case "redis":
+		if redisPool == nil {
+			redisPool = c.createRedisPool()
+		}
+		m.Store = mailer.NewRedisStore(redisPool, os.Getenv("REDIS_PREFIX"))
+	case "badger":
+		if badgerConn == nil {
+			badgerConn = c.createBadgerConn()
+		}
+		m.Store = mailer.NewBadgerStore(badgerConn)
+	}
+	return m, nil
 }
 
 func (c *Medego) createClientRedisCache() *cache.RedisCache {
@@ -315,6 +497,21 @@ func (c *Medego) BuildDSN() string {
 		if os.Getenv("DATABASE_PASS") != "" {
 			dsn = fmt.Sprintf("%s password=%s", dsn, os.Getenv("DATABASE_PASS"))
 		}
+	case "mysql", "mariadb":
+		tls := os.Getenv("DATABASE_SSL_MODE")
+		if tls == "" || tls == "disable" {
+			tls = "false"
+		}
+		dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?collation=utf8_unicode_ci&timeout=5s&parseTime=true&tls=%s&readTimeout=5s&multiStatements=true",
+			os.Getenv("DATABASE_USER"),
+			os.Getenv("DATABASE_PASS"),
+			os.Getenv("DATABASE_HOST"),
+			os.Getenv("DATABASE_PORT"),
+			os.Getenv("DATABASE_NAME"),
+			tls)
+	case "sqlite", "sqlite3":
+		// the path of the database file, for the sqlite3 driver of github.com/mattn/go-sqlite3
+		dsn = os.Getenv("DATABASE_NAME")
 	default:
 
 	}
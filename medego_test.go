@@ -0,0 +1,29 @@
+package medego
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestMedego_createMailer_Queue(t *testing.T) {
+	t.Setenv("MAIL_QUEUE", "database")
+
+	if _, err := (&Medego{}).createMailer(); err == nil {
+		t.Error("expected an error without database")
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "mail.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m, err := (&Medego{DB: Database{DataType: "sqlite", Pool: db}}).createMailer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Store == nil {
+		t.Error("expected the mails to be queued in the database")
+	}
+}
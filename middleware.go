@@ -1,14 +1,29 @@
 package medego
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
+	"github.com/PrinMeshia/medego/i18n"
 	"github.com/justinas/nosurf"
 )
 
+// sessionKey marks the context of the requests whose session was loaded by SessionLoad
+type sessionKey struct{}
+
+// SessionLoad loads the session of the request, and saves it once the request is handled
 func (c *Medego) SessionLoad(next http.Handler) http.Handler {
-	return c.Session.LoadAndSave(next)
+	return c.Session.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, true)))
+	}))
+}
+
+// sessionLoaded reports whether SessionLoad loaded the session of the request: reading a
+// session that was not loaded, e.g. in a middleware running before it, panics
+func (c *Medego) sessionLoaded(r *http.Request) bool {
+	loaded, _ := r.Context().Value(sessionKey{}).(bool)
+	return c.Session != nil && loaded
 }
 
 func (c *Medego) NoSurf(next http.Handler) http.Handler {
@@ -26,3 +41,35 @@ func (c *Medego) NoSurf(next http.Handler) http.Handler {
 	})
 	return csrfHandler
 }
+
+// Locale stores the locale of the request in its context, for translations: the locale of the
+// lang cookie, or else of the locale session key when Locale runs after SessionLoad, or else the
+// best match of Accept-Language, or else the default locale
+func (c *Medego) Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), c.requestLocale(r))))
+	})
+}
+
+func (c *Medego) requestLocale(r *http.Request) string {
+	if c.I18n == nil {
+		return ""
+	}
+
+	if cookie, err := r.Cookie("lang"); err == nil {
+		if locale := c.I18n.Supported(cookie.Value); locale != "" {
+			return locale
+		}
+	}
+
+	if c.sessionLoaded(r) {
+		if locale := c.I18n.Supported(c.Session.GetString(r.Context(), "locale")); locale != "" {
+			return locale
+		}
+	}
+
+	if locale := c.I18n.Match(r.Header.Get("Accept-Language")); locale != "" {
+		return locale
+	}
+	return c.I18n.DefaultLocale
+}
@@ -0,0 +1,147 @@
+package medego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PrinMeshia/medego/i18n"
+	"github.com/PrinMeshia/medego/pubsub"
+	"github.com/PrinMeshia/medego/ws"
+	"github.com/gorilla/websocket"
+)
+
+func TestMedego_Locale(t *testing.T) {
+	app := testApp()
+	app.I18n = i18n.New("en")
+	for _, locale := range []string{"en", "fr", "de"} {
+		app.I18n.Add(locale, map[string]string{"hello": locale})
+	}
+
+	ctx, err := app.Session.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Session.Put(ctx, "locale", "fr")
+	token, _, err := app.Session.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var locale string
+	handler := app.Locale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale = i18n.Locale(r.Context())
+	}))
+
+	var tests = []struct {
+		name     string
+		handler  http.Handler
+		cookies  []*http.Cookie
+		language string
+		expected string
+	}{
+		{"default", app.SessionLoad(handler), nil, "", "en"},
+		{"accept language", app.SessionLoad(handler), nil, "de-CH, fr;q=0.5", "de"},
+		{"session", app.SessionLoad(handler), []*http.Cookie{{Name: "session", Value: token}}, "de", "fr"},
+		{"cookie", app.SessionLoad(handler), []*http.Cookie{{Name: "session", Value: token}, {Name: "lang", Value: "de"}}, "", "de"},
+		{"session not loaded", handler, []*http.Cookie{{Name: "session", Value: token}}, "de", "de"},
+	}
+
+	for _, e := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range e.cookies {
+			r.AddCookie(cookie)
+		}
+		if e.language != "" {
+			r.Header.Set("Accept-Language", e.language)
+		}
+		locale = ""
+		e.handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		if locale != e.expected {
+			t.Errorf("%s: expected the locale %s, got %s", e.name, e.expected, locale)
+		}
+	}
+}
+
+func TestMedego_sessionUserID(t *testing.T) {
+	app := testApp()
+
+	var userID int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("login") {
+			app.Session.Put(r.Context(), "userID", 7)
+		}
+		userID = app.sessionUserID(r)
+	})
+
+	rr := httptest.NewRecorder()
+	app.SessionLoad(handler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?login", nil))
+	if userID != 7 {
+		t.Errorf("expected the user of the session, got %d", userID)
+	}
+
+	var tests = []struct {
+		name     string
+		handler  http.Handler
+		expected int
+	}{
+		{"session", app.SessionLoad(handler), 7},
+		{"session not loaded", handler, 0},
+	}
+
+	for _, e := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range rr.Result().Cookies() {
+			r.AddCookie(cookie)
+		}
+		userID = -1
+		e.handler.ServeHTTP(httptest.NewRecorder(), r)
+		if userID != e.expected {
+			t.Errorf("%s: expected the user %d, got %d", e.name, e.expected, userID)
+		}
+	}
+}
+
+func TestMedego_WebSocketSession(t *testing.T) {
+	app := testApp()
+	app.WebSocket = ws.NewHub(pubsub.NewMemory())
+	app.WebSocket.Session = app.sessionUserID
+	users := make(chan int, 1)
+	app.WebSocket.OnConnect = func(c *ws.Conn) { users <- c.UserID }
+	defer func() { _ = app.WebSocket.Shutdown(context.Background()) }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		app.Session.Put(r.Context(), "userID", 7)
+	})
+	mux.Handle("/ws", app.WebSocket)
+	server := httptest.NewServer(app.SessionLoad(mux))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// the connection is upgraded through the response writer of the session
+	header := http.Header{"Cookie": {resp.Header.Get("Set-Cookie")}}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	select {
+	case userID := <-users:
+		if userID != 7 {
+			t.Errorf("expected the user logged in the session, got %d", userID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("expected the connection to open")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/golang-migrate/migrate/v4/database/mysql"
@@ -13,8 +14,7 @@ import (
 )
 
 func (c *Medego) runMigration(dsn string, operation func(*migrate.Migrate) error) error {
-	rootPath := filepath.ToSlash(c.RootPath)
-	m, err := migrate.New("file://"+rootPath+"/migrations", dsn)
+	m, err := c.newMigrate(dsn)
 	if err != nil {
 		return err
 	}
@@ -28,6 +28,20 @@ func (c *Medego) runMigration(dsn string, operation func(*migrate.Migrate) error
 	return nil
 }
 
+// newMigrate reads the migrations embedded in FS, or else the migrations folder on disk
+func (c *Medego) newMigrate(dsn string) (*migrate.Migrate, error) {
+	if migrations := c.embedded("migrations"); migrations != nil {
+		source, err := iofs.New(migrations, ".")
+		if err != nil {
+			return nil, err
+		}
+		return migrate.NewWithSourceInstance("iofs", source, dsn)
+	}
+
+	rootPath := filepath.ToSlash(c.RootPath)
+	return migrate.New("file://"+rootPath+"/migrations", dsn)
+}
+
 func (c *Medego) MigrateUp(dsn string) error {
 	return c.runMigration(dsn, (*migrate.Migrate).Up)
 }
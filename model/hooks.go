@@ -0,0 +1,71 @@
+package model
+
+import "context"
+
+// BeforeInserter is implemented by models that need to run code before they are inserted.
+// Returning an error cancels the insert.
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// AfterInserter is implemented by models that need to run code after they are inserted
+type AfterInserter interface {
+	AfterInsert(ctx context.Context) error
+}
+
+// BeforeUpdater is implemented by models that need to run code before they are updated.
+// Returning an error cancels the update.
+type BeforeUpdater interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+// AfterUpdater is implemented by models that need to run code after they are updated
+type AfterUpdater interface {
+	AfterUpdate(ctx context.Context) error
+}
+
+// BeforeSaver is implemented by models that need to run code before they are inserted, updated
+// or upserted, before BeforeInsert and BeforeUpdate. Returning an error cancels the change.
+type BeforeSaver interface {
+	BeforeSave(ctx context.Context) error
+}
+
+// AfterSaver is implemented by models that need to run code after they are inserted, updated
+// or upserted, after AfterInsert and AfterUpdate
+type AfterSaver interface {
+	AfterSave(ctx context.Context) error
+}
+
+// BeforeDeleter is implemented by models that need to run code before they are deleted,
+// or soft deleted. Returning an error cancels the delete.
+type BeforeDeleter interface {
+	BeforeDelete(ctx context.Context) error
+}
+
+// AfterDeleter is implemented by models that need to run code after they are deleted
+type AfterDeleter interface {
+	AfterDelete(ctx context.Context) error
+}
+
+// Actions of the change events published by repositories, as <table>.<action>
+const (
+	Created  = "created"
+	Updated  = "updated"
+	Deleted  = "deleted"
+	Restored = "restored"
+	// Saved is published by Upsert, which cannot tell whether the record was inserted or updated
+	Saved = "saved"
+)
+
+// Change is the payload of the events published when a record changes
+type Change struct {
+	Table  string
+	Action string
+	ID     interface{}
+	Record interface{}
+}
+
+// EventName returns the name of the event published when a record of the table changes, e.g. users.created
+func EventName(table, action string) string {
+	return table + "." + action
+}
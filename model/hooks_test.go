@@ -0,0 +1,85 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var errHook = errors.New("hook failed")
+
+// hookedUser records the hooks called, and fails the one named by Fail
+type hookedUser struct {
+	ID    int      `db:"id,omitempty"`
+	Email string   `db:"email"`
+	Calls []string `db:"-"`
+	Fail  string   `db:"-"`
+}
+
+func (u *hookedUser) Table() string {
+	return "users"
+}
+
+func (u *hookedUser) call(name string) error {
+	u.Calls = append(u.Calls, name)
+	if u.Fail == name {
+		return errHook
+	}
+	return nil
+}
+
+func (u *hookedUser) BeforeInsert(ctx context.Context) error { return u.call("BeforeInsert") }
+func (u *hookedUser) AfterInsert(ctx context.Context) error  { return u.call("AfterInsert") }
+func (u *hookedUser) BeforeUpdate(ctx context.Context) error { return u.call("BeforeUpdate") }
+func (u *hookedUser) AfterUpdate(ctx context.Context) error  { return u.call("AfterUpdate") }
+func (u *hookedUser) BeforeSave(ctx context.Context) error   { return u.call("BeforeSave") }
+func (u *hookedUser) AfterSave(ctx context.Context) error    { return u.call("AfterSave") }
+func (u *hookedUser) BeforeDelete(ctx context.Context) error { return u.call("BeforeDelete") }
+func (u *hookedUser) AfterDelete(ctx context.Context) error  { return u.call("AfterDelete") }
+
+func TestRepository_Hooks(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository[*hookedUser](testSession(t)).WithEvents(nil)
+
+	u := &hookedUser{Email: "joe@example.com"}
+	if _, err := repo.Insert(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"BeforeSave", "BeforeInsert", "AfterInsert", "AfterSave",
+		"BeforeSave", "BeforeUpdate", "AfterUpdate", "AfterSave",
+	}
+	if !reflect.DeepEqual(u.Calls, expected) {
+		t.Errorf("expected the hooks %v, got %v", expected, u.Calls)
+	}
+
+	// a failing Before hook cancels the change
+	failing := &hookedUser{Email: "ann@example.com", Fail: "BeforeInsert"}
+	if _, err := repo.Insert(ctx, failing); !errors.Is(err, errHook) {
+		t.Errorf("expected the error of the hook, got %v", err)
+	}
+	if count, _ := repo.Count(ctx); count != 1 {
+		t.Errorf("expected the insert to be cancelled, got %d records", count)
+	}
+
+	// Upsert cannot tell an insert from an update
+	upserted := &hookedUser{Email: "joe@example.com"}
+	if _, err := repo.Upsert(ctx, upserted, "email"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"BeforeSave", "AfterSave"}; !reflect.DeepEqual(upserted.Calls, expected) {
+		t.Errorf("expected the hooks %v, got %v", expected, upserted.Calls)
+	}
+
+	// Delete calls the hooks of the record it finds
+	if err := repo.Delete(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := repo.Count(ctx); count != 0 {
+		t.Errorf("expected the record to be deleted, got %d records", count)
+	}
+}
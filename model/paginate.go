@@ -0,0 +1,70 @@
+package model
+
+import (
+	up "github.com/upper/db/v4"
+)
+
+// DefaultPerPage is the page size used when none is given
+const DefaultPerPage = 20
+
+// DefaultMaxPerPage is the largest page size a request may ask for, unless QueryOptions sets another
+const DefaultMaxPerPage = 100
+
+// PageMeta describes a page within a result set
+type PageMeta struct {
+	Page       uint   `json:"page" xml:"page"`
+	PerPage    uint   `json:"per_page" xml:"per_page"`
+	Total      uint64 `json:"total" xml:"total"`
+	TotalPages uint   `json:"total_pages" xml:"total_pages"`
+}
+
+// Paginated is implemented by pages of any type of record
+type Paginated interface {
+	Pagination() PageMeta
+}
+
+// Page holds one page of records and its metadata
+type Page[T any] struct {
+	Items []T      `json:"data" xml:"data"`
+	Meta  PageMeta `json:"meta" xml:"meta"`
+}
+
+// Pagination returns the metadata of the page
+func (p *Page[T]) Pagination() PageMeta {
+	return p.Meta
+}
+
+// Paginate returns the page-th page, starting at 1, of perPage records of query
+func Paginate[T any](query up.Result, page, perPage uint) (*Page[T], error) {
+	if page == 0 {
+		page = 1
+	}
+	if perPage == 0 {
+		perPage = DefaultPerPage
+	}
+
+	total, err := query.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []T{}
+	if err := query.Paginate(perPage).Page(page).All(&items); err != nil {
+		return nil, err
+	}
+
+	return &Page[T]{
+		Items: items,
+		Meta: PageMeta{
+			Page:       page,
+			PerPage:    perPage,
+			Total:      total,
+			TotalPages: uint((total + uint64(perPage) - 1) / uint64(perPage)),
+		},
+	}, nil
+}
+
+// PaginateQuery filters and sorts query as requested by q, then returns the requested page
+func PaginateQuery[T any](query up.Result, q *Query) (*Page[T], error) {
+	return Paginate[T](q.Apply(query), q.Page, q.PerPage)
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	up "github.com/upper/db/v4"
+)
+
+// ErrInvalidQuery is returned when a request asks for a page, sort or filter that is not allowed
+var ErrInvalidQuery = errors.New("invalid query")
+
+// QueryOptions lists the fields a request may sort and filter on, and the paging limits.
+// PerPage defaults to DefaultPerPage and MaxPerPage to DefaultMaxPerPage.
+type QueryOptions struct {
+	Sortable    []string
+	Filterable  []string
+	DefaultSort []string
+	PerPage     uint
+	MaxPerPage  uint
+}
+
+// Query holds the paging, sorting and filtering parameters of a request
+type Query struct {
+	Page    uint
+	PerPage uint
+	Sort    []string
+	Filter  up.Cond
+}
+
+// ParseQuery reads ?page=2&per_page=10&sort=-created_at,name&filter[email]=a@b.c from values.
+// Fields prefixed by - are sorted in descending order; a filter given several
+// times matches any of its values. Only the fields listed in opts are accepted.
+func ParseQuery(values url.Values, opts QueryOptions) (*Query, error) {
+	q := &Query{
+		Page:    1,
+		PerPage: opts.PerPage,
+		Sort:    opts.DefaultSort,
+		Filter:  up.Cond{},
+	}
+	if q.PerPage == 0 {
+		q.PerPage = DefaultPerPage
+	}
+
+	if page := values.Get("page"); page != "" {
+		n, err := strconv.ParseUint(page, 10, 32)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("%w: page must be a positive integer", ErrInvalidQuery)
+		}
+		q.Page = uint(n)
+	}
+
+	if perPage := values.Get("per_page"); perPage != "" {
+		n, err := strconv.ParseUint(perPage, 10, 32)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("%w: per_page must be a positive integer", ErrInvalidQuery)
+		}
+		q.PerPage = uint(n)
+	}
+	maxPerPage := opts.MaxPerPage
+	if maxPerPage == 0 {
+		maxPerPage = DefaultMaxPerPage
+	}
+	if q.PerPage > maxPerPage {
+		q.PerPage = maxPerPage
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		q.Sort = nil
+		for _, field := range strings.Split(sort, ",") {
+			if !slices.Contains(opts.Sortable, strings.TrimPrefix(field, "-")) {
+				return nil, fmt.Errorf("%w: cannot sort by %s", ErrInvalidQuery, field)
+			}
+			q.Sort = append(q.Sort, field)
+		}
+	}
+
+	for key, vals := range values {
+		if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+
+		field := strings.TrimSuffix(strings.TrimPrefix(key, "filter["), "]")
+		if !slices.Contains(opts.Filterable, field) {
+			return nil, fmt.Errorf("%w: cannot filter by %s", ErrInvalidQuery, field)
+		}
+
+		if len(vals) == 1 {
+			q.Filter[field] = vals[0]
+		} else {
+			q.Filter[field+" IN"] = vals
+		}
+	}
+
+	return q, nil
+}
+
+// Apply adds the filter and sort order of the query to a result set
+func (q *Query) Apply(res up.Result) up.Result {
+	if len(q.Filter) > 0 {
+		res = res.And(q.Filter)
+	}
+	if len(q.Sort) > 0 {
+		sort := make([]interface{}, len(q.Sort))
+		for i, field := range q.Sort {
+			sort[i] = field
+		}
+		res = res.OrderBy(sort...)
+	}
+	return res
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	up "github.com/upper/db/v4"
+)
+
+func TestParseQuery(t *testing.T) {
+	opts := QueryOptions{
+		Sortable:    []string{"name", "created_at"},
+		Filterable:  []string{"email", "name"},
+		DefaultSort: []string{"-created_at"},
+	}
+
+	var tests = []struct {
+		name     string
+		query    string
+		opts     QueryOptions
+		expected *Query
+		invalid  bool
+	}{
+		{"defaults", "", opts, &Query{Page: 1, PerPage: DefaultPerPage, Sort: []string{"-created_at"}, Filter: up.Cond{}}, false},
+		{"page", "page=3&per_page=10", opts, &Query{Page: 3, PerPage: 10, Sort: []string{"-created_at"}, Filter: up.Cond{}}, false},
+		{"sort", "sort=-name,created_at", opts, &Query{Page: 1, PerPage: DefaultPerPage, Sort: []string{"-name", "created_at"}, Filter: up.Cond{}}, false},
+		{"filter", "filter[email]=a@b.c", opts, &Query{Page: 1, PerPage: DefaultPerPage, Sort: []string{"-created_at"}, Filter: up.Cond{"email": "a@b.c"}}, false},
+		{"filter any of", "filter[name]=Ann&filter[name]=Joe", opts, &Query{Page: 1, PerPage: DefaultPerPage, Sort: []string{"-created_at"}, Filter: up.Cond{"name IN": []string{"Ann", "Joe"}}}, false},
+		{"other parameters", "q=x&filter=y", opts, &Query{Page: 1, PerPage: DefaultPerPage, Sort: []string{"-created_at"}, Filter: up.Cond{}}, false},
+		{"default max per page", "per_page=1000", opts, &Query{Page: 1, PerPage: DefaultMaxPerPage, Sort: []string{"-created_at"}, Filter: up.Cond{}}, false},
+		{"max per page", "per_page=50", QueryOptions{PerPage: 5, MaxPerPage: 25}, &Query{Page: 1, PerPage: 25, Filter: up.Cond{}}, false},
+		{"per page option", "", QueryOptions{PerPage: 5}, &Query{Page: 1, PerPage: 5, Filter: up.Cond{}}, false},
+		{"zero page", "page=0", opts, nil, true},
+		{"negative per page", "per_page=-1", opts, nil, true},
+		{"not a number", "page=two", opts, nil, true},
+		{"unsortable field", "sort=password", opts, nil, true},
+		{"unfilterable field", "filter[password]=x", opts, nil, true},
+	}
+
+	for _, e := range tests {
+		values, _ := url.ParseQuery(e.query)
+		q, err := ParseQuery(values, e.opts)
+		if e.invalid {
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("%s: expected ErrInvalidQuery, got %v", e.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", e.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(q, e.expected) {
+			t.Errorf("%s: expected %+v, got %+v", e.name, e.expected, q)
+		}
+	}
+}
+
+func TestRepository_Paginate(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository[*testUser](testSession(t)).WithEvents(nil)
+
+	for _, name := range []string{"Ann", "Bob", "Cid", "Dee", "Eve"} {
+		if _, err := repo.Insert(ctx, &testUser{Email: name + "@example.com", Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	values, _ := url.ParseQuery("page=2&per_page=2&sort=-name&filter[name]=Ann&filter[name]=Cid&filter[name]=Eve")
+	q, err := ParseQuery(values, QueryOptions{Sortable: []string{"name"}, Filterable: []string{"name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := repo.Paginate(ctx, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Name != "Ann" {
+		t.Errorf("expected Ann on the second page, got %+v", page.Items)
+	}
+	expected := PageMeta{Page: 2, PerPage: 2, Total: 3, TotalPages: 2}
+	if page.Meta != expected {
+		t.Errorf("expected %+v, got %+v", expected, page.Meta)
+	}
+}
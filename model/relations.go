@@ -0,0 +1,244 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+
+	up "github.com/upper/db/v4"
+)
+
+type relationKind int
+
+const (
+	hasMany relationKind = iota
+	hasOne
+	belongsTo
+)
+
+// Relation describes how the records of another model are attached to a field of a model
+type Relation struct {
+	kind       relationKind
+	foreignKey string
+	localKey   string
+	cond       up.Cond
+	orderBy    []interface{}
+}
+
+// Relations maps the name of a struct field, tagged db:"-", to the relation it is loaded from
+type Relations map[string]Relation
+
+// Relater is implemented by models that declare relations, which are then loaded with
+// Repository.With or Repository.Load, e.g.
+//
+//	func (u *User) Relations() model.Relations {
+//		return model.Relations{"Tokens": model.HasMany("user_id")}
+//	}
+type Relater interface {
+	Relations() Relations
+}
+
+// HasMany relates a slice field to the records whose foreignKey column holds the id of the model
+func HasMany(foreignKey string) Relation {
+	return Relation{kind: hasMany, foreignKey: foreignKey, localKey: "id"}
+}
+
+// HasOne relates a field to the first record whose foreignKey column holds the id of the model
+func HasOne(foreignKey string) Relation {
+	return Relation{kind: hasOne, foreignKey: foreignKey, localKey: "id"}
+}
+
+// BelongsTo relates a field to the record whose id is held by the foreignKey column of the model
+func BelongsTo(foreignKey string) Relation {
+	return Relation{kind: belongsTo, foreignKey: foreignKey, localKey: "id"}
+}
+
+// Key returns a copy of the relation matching the foreign key against the given column instead of id
+func (rel Relation) Key(column string) Relation {
+	rel.localKey = column
+	return rel
+}
+
+// Where returns a copy of the relation that only loads the related records matching cond
+func (rel Relation) Where(cond up.Cond) Relation {
+	rel.cond = cond
+	return rel
+}
+
+// OrderBy returns a copy of the relation that sorts the related records, e.g. OrderBy("-created_at")
+func (rel Relation) OrderBy(fields ...interface{}) Relation {
+	rel.orderBy = fields
+	return rel
+}
+
+// With returns a copy of the repository that eager loads the named relations of the records it
+// returns. Each relation is loaded with a single IN (...) query, whatever the number of records.
+func (r *Repository[T]) With(names ...string) *Repository[T] {
+	repo := *r
+	repo.with = append(append([]string{}, r.with...), names...)
+	return &repo
+}
+
+// Load loads the named relations of a record that was fetched without them
+func (r *Repository[T]) Load(ctx context.Context, item T, names ...string) error {
+	return r.loadRelations(ctx, []T{item}, names)
+}
+
+// LoadAll loads the named relations of records that were fetched without them, one query per relation
+func (r *Repository[T]) LoadAll(ctx context.Context, items []T, names ...string) error {
+	return r.loadRelations(ctx, items, names)
+}
+
+func (r *Repository[T]) loadRelations(ctx context.Context, items []T, names []string) error {
+	if len(items) == 0 || len(names) == 0 {
+		return nil
+	}
+
+	relater, ok := Model(newModel[T]()).(Relater)
+	if !ok {
+		return fmt.Errorf("model %s declares no relations", r.table)
+	}
+	relations := relater.Relations()
+
+	for _, name := range names {
+		rel, ok := relations[name]
+		if !ok {
+			return fmt.Errorf("model %s has no relation %q", r.table, name)
+		}
+		if err := r.loadRelation(ctx, items, name, rel); err != nil {
+			return fmt.Errorf("loading %s of %s: %w", name, r.table, err)
+		}
+	}
+	return nil
+}
+
+func (r *Repository[T]) loadRelation(ctx context.Context, items []T, name string, rel Relation) error {
+	field, ok := reflect.TypeOf(newModel[T]()).Elem().FieldByName(name)
+	if !ok {
+		return fmt.Errorf("no field %s", name)
+	}
+
+	// the struct type of the related records
+	related := field.Type
+	if rel.kind == hasMany {
+		if related.Kind() != reflect.Slice {
+			return fmt.Errorf("field %s must be a slice", name)
+		}
+		related = related.Elem()
+	}
+	if related.Kind() == reflect.Ptr {
+		related = related.Elem()
+	}
+
+	relatedModel, ok := reflect.New(related).Interface().(Model)
+	if !ok {
+		return fmt.Errorf("%s does not implement model.Model", related)
+	}
+
+	// columns matched between the records of the model and the related records
+	ownColumn, relatedColumn := rel.localKey, rel.foreignKey
+	if rel.kind == belongsTo {
+		ownColumn, relatedColumn = rel.foreignKey, rel.localKey
+	}
+
+	var keys []interface{}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		value, ok := columnField(item, ownColumn)
+		if !ok {
+			return fmt.Errorf("model %s has no column %s", r.table, ownColumn)
+		}
+		key, ok := relationKey(value)
+		if ok && !seen[fmt.Sprint(key)] {
+			seen[fmt.Sprint(key)] = true
+			keys = append(keys, key)
+		}
+	}
+
+	// group the related records by the value of their key
+	byKey := make(map[string][]reflect.Value)
+	if len(keys) > 0 {
+		res := r.session.WithContext(ctx).Collection(relatedModel.Table()).Find(up.Cond{relatedColumn + " IN": keys})
+		if _, softDeletes := columnField(relatedModel, "deleted_at"); softDeletes {
+			res = res.And(up.Cond{"deleted_at IS": nil})
+		}
+		if len(rel.cond) > 0 {
+			res = res.And(rel.cond)
+		}
+		if len(rel.orderBy) > 0 {
+			res = res.OrderBy(rel.orderBy...)
+		}
+
+		records := reflect.New(reflect.SliceOf(reflect.PointerTo(related)))
+		if err := res.All(records.Interface()); err != nil {
+			return err
+		}
+
+		for i := 0; i < records.Elem().Len(); i++ {
+			record := records.Elem().Index(i)
+			value, ok := columnField(record.Interface(), relatedColumn)
+			if !ok {
+				return fmt.Errorf("model %s has no column %s", relatedModel.Table(), relatedColumn)
+			}
+			if key, ok := relationKey(value); ok {
+				byKey[fmt.Sprint(key)] = append(byKey[fmt.Sprint(key)], record)
+			}
+		}
+	}
+
+	for _, item := range items {
+		value, _ := columnField(item, ownColumn)
+		var matches []reflect.Value
+		if key, ok := relationKey(value); ok {
+			matches = byKey[fmt.Sprint(key)]
+		}
+		target := reflect.ValueOf(item).Elem().FieldByIndex(field.Index)
+
+		if rel.kind == hasMany {
+			slice := reflect.MakeSlice(field.Type, 0, len(matches))
+			for _, record := range matches {
+				slice = reflect.Append(slice, pointerOrValue(record, field.Type.Elem()))
+			}
+			target.Set(slice)
+			continue
+		}
+
+		if len(matches) > 0 {
+			target.Set(pointerOrValue(matches[0], field.Type))
+		} else {
+			target.Set(reflect.Zero(field.Type))
+		}
+	}
+	return nil
+}
+
+// relationKey returns the value of a key column, with pointers dereferenced and types such as
+// sql.NullInt64 turned into their driver value, so that an *int and an int key match.
+// ok is false for a nil key, which relates to no record.
+func relationKey(value reflect.Value) (key interface{}, ok bool) {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, false
+		}
+		value = value.Elem()
+	}
+
+	key = value.Interface()
+	if valuer, isValuer := key.(driver.Valuer); isValuer {
+		v, err := valuer.Value()
+		if err != nil || v == nil {
+			return nil, false
+		}
+		key = v
+	}
+	return key, true
+}
+
+// pointerOrValue returns record, a pointer to a struct, as a value of type t, either that pointer or the struct
+func pointerOrValue(record reflect.Value, t reflect.Type) reflect.Value {
+	if t.Kind() == reflect.Ptr {
+		return record
+	}
+	return record.Elem()
+}
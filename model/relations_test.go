@@ -0,0 +1,186 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	up "github.com/upper/db/v4"
+)
+
+type author struct {
+	ID       int         `db:"id,omitempty"`
+	Email    string      `db:"email"`
+	Posts    []*post     `db:"-"`
+	Drafts   []post      `db:"-"`
+	Profile  *profile    `db:"-"`
+	Comments []*comment  `db:"-"`
+	Invalid  []*testUser `db:"-"`
+}
+
+func (a *author) Table() string {
+	return "users"
+}
+
+func (a *author) Relations() Relations {
+	return Relations{
+		"Posts":    HasMany("user_id").Where(up.Cond{"draft": false}).OrderBy("-title"),
+		"Drafts":   HasMany("user_id").Where(up.Cond{"draft": true}),
+		"Profile":  HasOne("user_id"),
+		"Comments": HasMany("user_id").OrderBy("title"),
+		"Invalid":  BelongsTo("user_id"),
+	}
+}
+
+type post struct {
+	ID        int        `db:"id,omitempty"`
+	UserID    int        `db:"user_id"`
+	Title     string     `db:"title"`
+	Draft     bool       `db:"draft"`
+	DeletedAt *time.Time `db:"deleted_at"`
+	Author    *author    `db:"-"`
+}
+
+func (p *post) Table() string {
+	return "posts"
+}
+
+func (p *post) Relations() Relations {
+	return Relations{"Author": BelongsTo("user_id")}
+}
+
+// comment reads the posts through a nullable foreign key
+type comment struct {
+	ID        int        `db:"id,omitempty"`
+	UserID    *int       `db:"user_id"`
+	Title     string     `db:"title"`
+	DeletedAt *time.Time `db:"deleted_at"`
+	Author    *author    `db:"-"`
+}
+
+func (c *comment) Table() string {
+	return "posts"
+}
+
+func (c *comment) Relations() Relations {
+	return Relations{"Author": BelongsTo("user_id")}
+}
+
+type profile struct {
+	ID     int    `db:"id,omitempty"`
+	UserID int    `db:"user_id"`
+	Bio    string `db:"bio"`
+}
+
+func (p *profile) Table() string {
+	return "profiles"
+}
+
+func TestRepository_Relations(t *testing.T) {
+	ctx := context.Background()
+	session := testSession(t)
+	_, err := session.SQL().Exec(`
+CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, title TEXT, draft BOOLEAN, deleted_at DATETIME);
+CREATE TABLE profiles (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, bio TEXT);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authors := NewRepository[*author](session).WithEvents(nil)
+	posts := NewRepository[*post](session).WithEvents(nil)
+
+	ann, bob := &author{Email: "ann@example.com"}, &author{Email: "bob@example.com"}
+	for _, a := range []*author{ann, bob} {
+		if _, err := authors.Insert(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	for _, p := range []*post{
+		{UserID: ann.ID, Title: "a"},
+		{UserID: ann.ID, Title: "b"},
+		{UserID: ann.ID, Title: "draft", Draft: true},
+		{UserID: ann.ID, Title: "deleted", DeletedAt: &now},
+		{UserID: bob.ID, Title: "c"},
+	} {
+		if _, err := posts.Insert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := session.Collection("profiles").Insert(&profile{UserID: bob.ID, Bio: "Bob's bio"}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := authors.With("Posts", "Profile").All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 authors, got %d", len(all))
+	}
+	if titles := postTitles(all[0].Posts); titles != "b,a" {
+		t.Errorf("expected the published posts of ann, last first, got %s", titles)
+	}
+	if all[0].Profile != nil {
+		t.Errorf("expected ann to have no profile, got %+v", all[0].Profile)
+	}
+	if titles := postTitles(all[1].Posts); titles != "c" || all[1].Profile == nil || all[1].Profile.Bio != "Bob's bio" {
+		t.Errorf("expected the posts and profile of bob, got %s and %+v", titles, all[1].Profile)
+	}
+
+	// lazy loading, into a slice of structs
+	found, err := authors.Find(ctx, ann.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Posts != nil {
+		t.Error("expected the relations to be loaded only when asked")
+	}
+	if err := authors.Load(ctx, found, "Drafts"); err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Drafts) != 1 || found.Drafts[0].Title != "draft" {
+		t.Errorf("expected the drafts of ann, got %+v", found.Drafts)
+	}
+
+	written, err := posts.With("Author").All(ctx, up.Cond{"title IN": []string{"a", "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 2 || written[0].Author.Email != "ann@example.com" || written[1].Author.Email != "bob@example.com" {
+		t.Errorf("expected the authors of the posts, got %+v", written)
+	}
+
+	// a nullable foreign key matches by value, and relates nothing when nil
+	if _, err := session.Collection("posts").Insert(&comment{Title: "anonymous"}); err != nil {
+		t.Fatal(err)
+	}
+	comments, err := NewRepository[*comment](session).With("Author").All(ctx, up.Cond{"title IN": []string{"a", "anonymous"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 || comments[0].Author == nil || comments[0].Author.Email != "ann@example.com" || comments[1].Author != nil {
+		t.Errorf("expected the author of a and none for anonymous, got %+v", comments)
+	}
+	if err := authors.Load(ctx, found, "Comments"); err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Comments) != 3 || found.Comments[0].Title != "a" || *found.Comments[0].UserID != ann.ID {
+		t.Errorf("expected the posts of ann through the nullable key, got %+v", found.Comments)
+	}
+
+	for _, name := range []string{"Missing", "Invalid"} {
+		if err := authors.Load(ctx, found, name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func postTitles(posts []*post) string {
+	titles := make([]string, len(posts))
+	for i, p := range posts {
+		titles[i] = p.Title
+	}
+	return strings.Join(titles, ",")
+}
@@ -0,0 +1,428 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/PrinMeshia/medego/events"
+	up "github.com/upper/db/v4"
+)
+
+// ErrStaleRecord is returned when updating a record that was changed since it was read
+var ErrStaleRecord = errors.New("record was changed by another update")
+
+// ErrNotFound is returned when no record matches, by Find as well as by Update.
+// It is the up.ErrNoMoreRows error of upper/db.
+var ErrNotFound = up.ErrNoMoreRows
+
+// Repository provides typed database operations for a model using an upper/db session.
+// T is a pointer to the model struct, e.g. Repository[*User].
+//
+// Models with a deleted_at column, such as DeletedAt *time.Time `db:"deleted_at"`, are soft
+// deleted: Delete sets the column and queries leave those records out unless WithTrashed is used.
+// Models implementing the hook interfaces, such as BeforeInserter, are called around each change,
+// and a Change event named <table>.<action> is published on the bus of the repository.
+type Repository[T Model] struct {
+	session     up.Session
+	table       string
+	softDeletes bool
+	withTrashed bool
+	with        []string
+	events      *events.Bus
+}
+
+// NewRepository returns the repository of the model T, publishing its changes on events.Default
+func NewRepository[T Model](session up.Session) *Repository[T] {
+	item := newModel[T]()
+	_, softDeletes := columnField(item, "deleted_at")
+
+	return &Repository[T]{
+		session:     session,
+		table:       item.Table(),
+		softDeletes: softDeletes,
+		events:      events.Default,
+	}
+}
+
+// WithTrashed returns a copy of the repository whose queries include soft deleted records
+func (r *Repository[T]) WithTrashed() *Repository[T] {
+	repo := *r
+	repo.withTrashed = true
+	return &repo
+}
+
+// WithEvents returns a copy of the repository publishing its changes on bus, or nowhere when bus is nil
+func (r *Repository[T]) WithEvents(bus *events.Bus) *Repository[T] {
+	repo := *r
+	repo.events = bus
+	return &repo
+}
+
+// Table returns the table of the model
+func (r *Repository[T]) Table() string {
+	return r.table
+}
+
+// Query returns the result set matching the conditions, for further ordering or paging
+func (r *Repository[T]) Query(ctx context.Context, conds ...interface{}) up.Result {
+	res := r.collection(ctx).Find(conds...)
+	if r.softDeletes && !r.withTrashed {
+		res = res.And(up.Cond{"deleted_at IS": nil})
+	}
+	return res
+}
+
+// Find gets one record by id
+func (r *Repository[T]) Find(ctx context.Context, id interface{}) (T, error) {
+	return r.FindOneBy(ctx, "id", id)
+}
+
+// FindOneBy gets the first record whose field is equal to value
+func (r *Repository[T]) FindOneBy(ctx context.Context, field string, value interface{}) (T, error) {
+	item := newModel[T]()
+	if err := r.Query(ctx, up.Cond{field: value}).One(item); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	if err := r.loadRelations(ctx, []T{item}, r.with); err != nil {
+		var zero T
+		return zero, err
+	}
+	return item, nil
+}
+
+// All gets every record matching the conditions
+func (r *Repository[T]) All(ctx context.Context, conds ...interface{}) ([]T, error) {
+	var all []T
+	if err := r.Query(ctx, conds...).All(&all); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadRelations(ctx, all, r.with); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// Count returns the number of records matching the conditions
+func (r *Repository[T]) Count(ctx context.Context, conds ...interface{}) (uint64, error) {
+	return r.Query(ctx, conds...).Count()
+}
+
+// Paginate returns the page of records requested by q
+func (r *Repository[T]) Paginate(ctx context.Context, q *Query) (*Page[T], error) {
+	page, err := PaginateQuery[T](r.Query(ctx), q)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadRelations(ctx, page.Items, r.with); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Insert inserts a record, setting its created_at and updated_at columns, and returns its id
+func (r *Repository[T]) Insert(ctx context.Context, item T) (int, error) {
+	if err := beforeSave(ctx, item); err != nil {
+		return 0, err
+	}
+	if hook, ok := Model(item).(BeforeInserter); ok {
+		if err := hook.BeforeInsert(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	now := time.Now()
+	if createdAt, ok := columnField(item, "created_at"); ok {
+		if t, ok := createdAt.Interface().(time.Time); ok && t.IsZero() {
+			setColumn(item, "created_at", now)
+		}
+	}
+	setColumn(item, "updated_at", now)
+	if version, ok := columnField(item, "version"); ok && version.IsZero() {
+		setColumn(item, "version", 1)
+	}
+
+	res, err := r.collection(ctx).Insert(item)
+	if err != nil {
+		return 0, err
+	}
+
+	id := insertID(res.ID())
+	if id != 0 {
+		setColumn(item, "id", id)
+	}
+
+	if hook, ok := Model(item).(AfterInserter); ok {
+		if err := hook.AfterInsert(ctx); err != nil {
+			return id, err
+		}
+	}
+	if err := afterSave(ctx, item); err != nil {
+		return id, err
+	}
+
+	r.publish(ctx, Created, id, item)
+	return id, nil
+}
+
+// Update saves a record found by its id, setting its updated_at column.
+// Models with a version column are locked optimistically: the update only applies when the
+// version of the row is still the version of the record, and increments it, otherwise
+// ErrStaleRecord is returned. ErrNotFound is returned when no record has the id, which
+// includes soft deleted records unless WithTrashed is used.
+func (r *Repository[T]) Update(ctx context.Context, item T) error {
+	id, ok := columnField(item, "id")
+	if !ok {
+		return errors.New("model has no id column")
+	}
+
+	if err := beforeSave(ctx, item); err != nil {
+		return err
+	}
+	if hook, ok := Model(item).(BeforeUpdater); ok {
+		if err := hook.BeforeUpdate(ctx); err != nil {
+			return err
+		}
+	}
+
+	cond := up.Cond{"id": id.Interface()}
+	if r.softDeletes && !r.withTrashed {
+		cond["deleted_at IS"] = nil
+	}
+
+	version, versioned := columnField(item, "version")
+	var current interface{}
+	if versioned {
+		current = version.Interface()
+		cond["version"] = current
+		if !incrementVersion(version) {
+			return errors.New("the version column of the model must be an integer")
+		}
+	}
+
+	setColumn(item, "updated_at", time.Now())
+	res, err := r.session.WithContext(ctx).SQL().Update(r.table).Set(item).Where(cond).Exec()
+	if err == nil {
+		var affected int64
+		if affected, err = res.RowsAffected(); err == nil && affected == 0 {
+			err = r.notUpdated(ctx, cond, versioned)
+		}
+	}
+	if err != nil {
+		// the record keeps the version it was read with, so that it can be saved again
+		if versioned {
+			version.Set(reflect.ValueOf(current))
+		}
+		return err
+	}
+
+	if hook, ok := Model(item).(AfterUpdater); ok {
+		if err := hook.AfterUpdate(ctx); err != nil {
+			return err
+		}
+	}
+	if err := afterSave(ctx, item); err != nil {
+		return err
+	}
+
+	r.publish(ctx, Updated, id.Interface(), item)
+	return nil
+}
+
+// notUpdated returns the error of an update that changed no row: ErrStaleRecord for a
+// versioned record, ErrNotFound when no record matches. MySQL does not count the rows left
+// unchanged, so an unversioned record that still exists is not an error.
+func (r *Repository[T]) notUpdated(ctx context.Context, cond up.Cond, versioned bool) error {
+	if versioned {
+		return ErrStaleRecord
+	}
+
+	exists, err := r.session.WithContext(ctx).Collection(r.table).Find(cond).Exists()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete deletes a record by id. Records of models with a deleted_at column are only
+// marked as deleted, and can be brought back with Restore.
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	return r.delete(ctx, id, !r.softDeletes)
+}
+
+// ForceDelete deletes a record by id, even when the model is soft deleted
+func (r *Repository[T]) ForceDelete(ctx context.Context, id interface{}) error {
+	return r.WithTrashed().delete(ctx, id, true)
+}
+
+// Restore brings back a soft deleted record
+func (r *Repository[T]) Restore(ctx context.Context, id interface{}) error {
+	if !r.softDeletes {
+		return errors.New("model has no deleted_at column")
+	}
+
+	res := r.collection(ctx).Find(up.Cond{"id": id})
+	if err := res.Update(map[string]interface{}{"deleted_at": nil}); err != nil {
+		return err
+	}
+
+	item, err := r.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	r.publish(ctx, Restored, id, item)
+	return nil
+}
+
+func (r *Repository[T]) delete(ctx context.Context, id interface{}, force bool) error {
+	item, err := r.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if hook, ok := Model(item).(BeforeDeleter); ok {
+		if err := hook.BeforeDelete(ctx); err != nil {
+			return err
+		}
+	}
+
+	res := r.collection(ctx).Find(up.Cond{"id": id})
+	if force {
+		err = res.Delete()
+	} else {
+		now := time.Now()
+		err = res.Update(map[string]interface{}{"deleted_at": now})
+		if !setColumn(item, "deleted_at", now) {
+			setColumn(item, "deleted_at", &now)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if hook, ok := Model(item).(AfterDeleter); ok {
+		if err := hook.AfterDelete(ctx); err != nil {
+			return err
+		}
+	}
+
+	r.publish(ctx, Deleted, id, item)
+	return nil
+}
+
+// beforeSave calls the BeforeSave hook of the record, when it has one
+func beforeSave(ctx context.Context, item Model) error {
+	if hook, ok := item.(BeforeSaver); ok {
+		return hook.BeforeSave(ctx)
+	}
+	return nil
+}
+
+// afterSave calls the AfterSave hook of the record, when it has one
+func afterSave(ctx context.Context, item Model) error {
+	if hook, ok := item.(AfterSaver); ok {
+		return hook.AfterSave(ctx)
+	}
+	return nil
+}
+
+// publish sends a Change event for the record to the bus of the repository
+func (r *Repository[T]) publish(ctx context.Context, action string, id interface{}, item T) {
+	if r.events == nil {
+		return
+	}
+
+	r.events.Publish(ctx, EventName(r.table, action), Change{
+		Table:  r.table,
+		Action: action,
+		ID:     id,
+		Record: item,
+	})
+}
+
+func (r *Repository[T]) collection(ctx context.Context) up.Collection {
+	return r.session.WithContext(ctx).Collection(r.table)
+}
+
+// newModel allocates the struct T points to
+func newModel[T Model]() T {
+	var zero T
+	return reflect.New(reflect.TypeOf(zero).Elem()).Interface().(T)
+}
+
+// insertID converts the id returned by an insert to an int
+func insertID(id up.ID) int {
+	switch v := id.(type) {
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case uint64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// columnField returns the struct field mapped to a column by its db tag
+func columnField(item interface{}, column string) (reflect.Value, bool) {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("db"), ",")
+		if name == column {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setColumn sets the field mapped to a column, when it exists and value can be assigned to it
+func setColumn(item interface{}, column string, value interface{}) bool {
+	field, ok := columnField(item, column)
+	if !ok || !field.CanSet() {
+		return false
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(field.Type()):
+		field.Set(v)
+	case isNumber(v.Kind()) && isNumber(field.Kind()):
+		field.Set(v.Convert(field.Type()))
+	default:
+		return false
+	}
+	return true
+}
+
+// incrementVersion adds one to an integer version field
+func incrementVersion(field reflect.Value) bool {
+	switch {
+	case field.CanInt():
+		field.SetInt(field.Int() + 1)
+	case field.CanUint():
+		field.SetUint(field.Uint() + 1)
+	default:
+		return false
+	}
+	return true
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
@@ -0,0 +1,241 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PrinMeshia/medego/events"
+	up "github.com/upper/db/v4"
+	sqliteadapter "github.com/upper/db/v4/adapter/sqlite"
+)
+
+type testUser struct {
+	ID        int        `db:"id,omitempty"`
+	Email     string     `db:"email"`
+	Name      string     `db:"name"`
+	Version   int        `db:"version"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+func (u *testUser) Table() string {
+	return "users"
+}
+
+// unversionedUser is a soft deleted model of the users table without optimistic locking
+type unversionedUser struct {
+	ID        int        `db:"id,omitempty"`
+	Email     string     `db:"email"`
+	UpdatedAt time.Time  `db:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+func (u *unversionedUser) Table() string {
+	return "users"
+}
+
+const testSchema = `
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL DEFAULT '',
+	version INTEGER NOT NULL DEFAULT 1,
+	created_at DATETIME,
+	updated_at DATETIME,
+	deleted_at DATETIME
+);`
+
+// testSession returns a session on a new sqlite database holding the test tables
+func testSession(t *testing.T) up.Session {
+	t.Helper()
+
+	session, err := sqliteadapter.Open(sqliteadapter.ConnectionURL{Database: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = session.Close() })
+
+	if _, err := session.SQL().Exec(testSchema); err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func TestColumnField(t *testing.T) {
+	u := &testUser{ID: 3, Email: "a@b.c"}
+
+	var tests = []struct {
+		column   string
+		found    bool
+		expected interface{}
+	}{
+		{"id", true, 3},
+		{"email", true, "a@b.c"},
+		{"deleted_at", true, (*time.Time)(nil)},
+		{"missing", false, nil},
+	}
+
+	for _, e := range tests {
+		field, ok := columnField(u, e.column)
+		if ok != e.found {
+			t.Errorf("%s: expected found to be %t", e.column, e.found)
+			continue
+		}
+		if ok && !reflect.DeepEqual(field.Interface(), e.expected) {
+			t.Errorf("%s: expected %v, got %v", e.column, e.expected, field.Interface())
+		}
+	}
+
+	if _, ok := columnField("not a struct", "id"); ok {
+		t.Error("expected no column on a non-struct value")
+	}
+}
+
+func TestSetColumn(t *testing.T) {
+	now := time.Now()
+
+	var tests = []struct {
+		name   string
+		column string
+		value  interface{}
+		set    bool
+	}{
+		{"same type", "name", "Joe", true},
+		{"number converted", "id", int64(7), true},
+		{"pointer", "deleted_at", &now, true},
+		{"value of a pointer field", "deleted_at", now, false},
+		{"wrong type", "email", 4, false},
+		{"missing column", "missing", 1, false},
+	}
+
+	for _, e := range tests {
+		u := &testUser{}
+		if set := setColumn(u, e.column, e.value); set != e.set {
+			t.Errorf("%s: expected set to be %t", e.name, e.set)
+		}
+	}
+
+	u := &testUser{}
+	setColumn(u, "id", int64(7))
+	setColumn(u, "deleted_at", &now)
+	if u.ID != 7 || u.DeletedAt != &now {
+		t.Errorf("expected the fields to be set, got %+v", u)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	bus := events.New()
+	repo := NewRepository[*testUser](testSession(t)).WithEvents(bus)
+
+	var published []string
+	bus.Subscribe("users.*", func(ctx context.Context, e events.Event) {
+		published = append(published, e.Name)
+	})
+
+	u := &testUser{Email: "joe@example.com", Name: "Joe"}
+	id, err := repo.Insert(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == 0 || u.ID != id || u.Version != 1 || u.CreatedAt.IsZero() || u.UpdatedAt.IsZero() {
+		t.Errorf("expected the id, version and timestamps to be set, got %+v", u)
+	}
+
+	found, err := repo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Email != "joe@example.com" {
+		t.Errorf("expected the inserted record, got %+v", found)
+	}
+
+	found.Name = "Joseph"
+	if err := repo.Update(ctx, found); err != nil {
+		t.Fatal(err)
+	}
+	if found.Version != 2 {
+		t.Errorf("expected the version to be incremented, got %d", found.Version)
+	}
+
+	all, err := repo.All(ctx, up.Cond{"name": "Joseph"})
+	if err != nil || len(all) != 1 {
+		t.Errorf("expected the updated record, got %d: %v", len(all), err)
+	}
+
+	if err := repo.ForceDelete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := repo.WithTrashed().Count(ctx); count != 0 {
+		t.Errorf("expected the record to be deleted, got %d", count)
+	}
+
+	expected := []string{"users.created", "users.updated", "users.deleted"}
+	if !reflect.DeepEqual(published, expected) {
+		t.Errorf("expected the events %v, got %v", expected, published)
+	}
+}
+
+func TestRepository_SoftDelete(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository[*testUser](testSession(t)).WithEvents(nil)
+
+	ann := &testUser{Email: "ann@example.com"}
+	bob := &testUser{Email: "bob@example.com"}
+	for _, u := range []*testUser{ann, bob} {
+		if _, err := repo.Insert(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.Delete(ctx, ann.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Find(ctx, ann.ID); !errors.Is(err, up.ErrNoMoreRows) {
+		t.Errorf("expected the deleted record to be left out, got %v", err)
+	}
+	if count, _ := repo.Count(ctx); count != 1 {
+		t.Errorf("expected one record, got %d", count)
+	}
+	trashed, err := repo.WithTrashed().Find(ctx, ann.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trashed.DeletedAt == nil {
+		t.Error("expected deleted_at to be set")
+	}
+
+	// a deleted record cannot be updated until it is restored
+	if err := repo.Update(ctx, trashed); !errors.Is(err, ErrStaleRecord) {
+		t.Errorf("expected the update of a deleted record to fail, got %v", err)
+	}
+	unversioned := NewRepository[*unversionedUser](repo.session).WithEvents(nil)
+	for _, u := range []*unversionedUser{{ID: ann.ID, Email: "ann@example.com"}, {ID: 99, Email: "joe@example.com"}} {
+		if err := unversioned.Update(ctx, u); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected the update of record %d to be not found, got %v", u.ID, err)
+		}
+	}
+	if err := unversioned.WithTrashed().Update(ctx, &unversionedUser{ID: ann.ID, Email: "ann@example.com", DeletedAt: trashed.DeletedAt}); err != nil {
+		t.Errorf("expected the update of a trashed record to apply, got %v", err)
+	}
+
+	if err := repo.Restore(ctx, ann.ID); err != nil {
+		t.Fatal(err)
+	}
+	if restored, err := repo.Find(ctx, ann.ID); err != nil || restored.DeletedAt != nil {
+		t.Errorf("expected the record to be restored, got %+v: %v", restored, err)
+	}
+
+	if err := repo.ForceDelete(ctx, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := repo.WithTrashed().Count(ctx); count != 1 {
+		t.Errorf("expected the record to be deleted, got %d records", count)
+	}
+}
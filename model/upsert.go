@@ -0,0 +1,175 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	up "github.com/upper/db/v4"
+)
+
+// Upsert inserts a record, or updates the existing record when it conflicts with one on the
+// conflict columns, which must be covered by a unique index, and returns the id of the record.
+// It uses ON CONFLICT on postgres and sqlite, and ON DUPLICATE KEY UPDATE on mysql and mariadb,
+// which matches on any unique index of the table.
+//
+// The deleted_at column is never updated: a soft deleted record stays deleted until it is
+// restored. As Upsert cannot tell whether it inserts or updates, it calls the BeforeSave and
+// AfterSave hooks of the model, not the insert and update ones.
+func (r *Repository[T]) Upsert(ctx context.Context, item T, conflict ...string) (int, error) {
+	if len(conflict) == 0 {
+		return 0, errors.New("upsert needs at least one conflict column")
+	}
+
+	dialect, err := dialectOf(r.session)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := beforeSave(ctx, item); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	if createdAt, ok := columnField(item, "created_at"); ok {
+		if t, ok := createdAt.Interface().(time.Time); ok && t.IsZero() {
+			setColumn(item, "created_at", now)
+		}
+	}
+	setColumn(item, "updated_at", now)
+	if version, ok := columnField(item, "version"); ok && version.IsZero() {
+		setColumn(item, "version", 1)
+	}
+
+	columns, values := columnValues(item)
+
+	quoted := make([]string, len(columns))
+	var updates []string
+	for i, column := range columns {
+		quoted[i] = dialect.quote(column)
+		if column == "id" || column == "created_at" || column == "deleted_at" || slices.Contains(conflict, column) {
+			continue
+		}
+
+		switch {
+		case column == "version":
+			updates = append(updates, fmt.Sprintf("%s = %s.%s + 1", quoted[i], dialect.quote(r.table), quoted[i]))
+		case dialect == mysql:
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", quoted[i], quoted[i]))
+		default:
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quoted[i], quoted[i]))
+		}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		dialect.quote(r.table), strings.Join(quoted, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+
+	var id int64
+	if dialect == mysql {
+		// LAST_INSERT_ID(id) makes the id of an updated row available as the insert id
+		updates = append(updates, "`id` = LAST_INSERT_ID(`id`)")
+		query += " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+
+		res, err := r.session.WithContext(ctx).SQL().Exec(query, values...)
+		if err != nil {
+			return 0, err
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return 0, err
+		}
+	} else {
+		quotedConflict := make([]string, len(conflict))
+		for i, column := range conflict {
+			quotedConflict[i] = dialect.quote(column)
+		}
+
+		query += " ON CONFLICT (" + strings.Join(quotedConflict, ", ") + ")"
+		if len(updates) > 0 {
+			query += " DO UPDATE SET " + strings.Join(updates, ", ")
+		} else {
+			// a no-op update, so that RETURNING gives back the existing row
+			query += fmt.Sprintf(" DO UPDATE SET %s = EXCLUDED.%s", quotedConflict[0], quotedConflict[0])
+		}
+		query += " RETURNING " + dialect.quote("id")
+
+		row, err := r.session.WithContext(ctx).SQL().QueryRow(query, values...)
+		if err != nil {
+			return 0, err
+		}
+		if err := row.Scan(&id); err != nil {
+			return 0, err
+		}
+	}
+
+	setColumn(item, "id", id)
+	if err := afterSave(ctx, item); err != nil {
+		return int(id), err
+	}
+
+	r.publish(ctx, Saved, int(id), item)
+	return int(id), nil
+}
+
+type sqlDialect string
+
+const (
+	postgres sqlDialect = "postgres"
+	mysql    sqlDialect = "mysql"
+	sqlite   sqlDialect = "sqlite"
+)
+
+func (d sqlDialect) quote(name string) string {
+	if d == mysql {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+// dialectOf finds the database behind a session from the package of its driver
+func dialectOf(session up.Session) (sqlDialect, error) {
+	var pkg string
+	if db, ok := session.Driver().(*sql.DB); ok {
+		pkg = reflect.Indirect(reflect.ValueOf(db.Driver())).Type().PkgPath()
+	} else if url := session.ConnectionURL(); url != nil {
+		pkg = reflect.Indirect(reflect.ValueOf(url)).Type().PkgPath()
+	}
+
+	switch {
+	case strings.Contains(pkg, "pgx"), strings.Contains(pkg, "pq"), strings.Contains(pkg, "postgres"):
+		return postgres, nil
+	case strings.Contains(pkg, "mysql"):
+		return mysql, nil
+	case strings.Contains(pkg, "sqlite"):
+		return sqlite, nil
+	default:
+		return "", fmt.Errorf("cannot tell the database of the session, driver package %q", pkg)
+	}
+}
+
+// columnValues returns the columns of a record and their values, leaving out
+// zero values of omitempty columns such as the id of a new record
+func columnValues(item interface{}) ([]string, []interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	t := v.Type()
+
+	var columns []string
+	var values []interface{}
+	for i := 0; i < t.NumField(); i++ {
+		name, options, _ := strings.Cut(t.Field(i).Tag.Get("db"), ",")
+		if name == "" || name == "-" || !t.Field(i).IsExported() {
+			continue
+		}
+		if strings.Contains(options, "omitempty") && v.Field(i).IsZero() {
+			continue
+		}
+
+		columns = append(columns, name)
+		values = append(values, v.Field(i).Interface())
+	}
+	return columns, values
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestColumnValues(t *testing.T) {
+	type record struct {
+		ID       int    `db:"id,omitempty"`
+		Name     string `db:"name"`
+		Note     string `db:"note,omitempty"`
+		Skipped  string `db:"-"`
+		Untagged string
+		private  string `db:"private"`
+	}
+
+	var tests = []struct {
+		name    string
+		record  record
+		columns []string
+		values  []interface{}
+	}{
+		{"new record", record{Name: "Ann", private: "x"}, []string{"name"}, []interface{}{"Ann"}},
+		{"zero value without omitempty", record{}, []string{"name"}, []interface{}{""}},
+		{"every column", record{ID: 2, Name: "Ann", Note: "n", Skipped: "s", Untagged: "u"}, []string{"id", "name", "note"}, []interface{}{2, "Ann", "n"}},
+	}
+
+	for _, e := range tests {
+		columns, values := columnValues(&e.record)
+		if !reflect.DeepEqual(columns, e.columns) || !reflect.DeepEqual(values, e.values) {
+			t.Errorf("%s: expected %v %v, got %v %v", e.name, e.columns, e.values, columns, values)
+		}
+	}
+}
+
+func TestRepository_Upsert(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository[*testUser](testSession(t)).WithEvents(nil)
+
+	id, err := repo.Upsert(ctx, &testUser{Email: "joe@example.com", Name: "Joe"}, "email")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &testUser{Email: "joe@example.com", Name: "Joseph"}
+	updated, err := repo.Upsert(ctx, u, "email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != id || u.ID != id {
+		t.Errorf("expected the id %d of the existing record, got %d", id, updated)
+	}
+
+	found, err := repo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Name != "Joseph" || found.Version != 2 {
+		t.Errorf("expected the record to be updated, got %+v", found)
+	}
+
+	// a soft deleted record is updated but stays deleted
+	if err := repo.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Upsert(ctx, &testUser{Email: "joe@example.com", Name: "Jo"}, "email"); err != nil {
+		t.Fatal(err)
+	}
+	trashed, err := repo.WithTrashed().Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trashed.Name != "Jo" || trashed.DeletedAt == nil {
+		t.Errorf("expected the record to be updated and still deleted, got %+v", trashed)
+	}
+
+	if _, err := repo.Upsert(ctx, &testUser{Email: "ann@example.com"}); err == nil {
+		t.Error("expected an error without conflict columns")
+	}
+}
+
+func TestRepository_Update_Stale(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository[*testUser](testSession(t)).WithEvents(nil)
+
+	if _, err := repo.Insert(ctx, &testUser{Email: "ann@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	id, err := repo.Insert(ctx, &testUser{Email: "joe@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := repo.Find(ctx, id)
+	second, _ := repo.Find(ctx, id)
+
+	first.Name = "first"
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	second.Name = "second"
+	if err := repo.Update(ctx, second); !errors.Is(err, ErrStaleRecord) {
+		t.Fatalf("expected ErrStaleRecord, got %v", err)
+	}
+	if second.Version != 1 {
+		t.Errorf("expected the stale record to keep its version, got %d", second.Version)
+	}
+
+	// a failing update keeps the version too
+	first.Email = "ann@example.com"
+	if err := repo.Update(ctx, first); err == nil {
+		t.Fatal("expected the unique index to fail the update")
+	}
+	if first.Version != 2 {
+		t.Errorf("expected the failed record to keep its version, got %d", first.Version)
+	}
+
+	// so that the record saves once it is fixed
+	first.Email = "joe@example.com"
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Name != "first" || found.Version != 3 {
+		t.Errorf("expected the first update to be kept, got %+v", found)
+	}
+}
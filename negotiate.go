@@ -0,0 +1,181 @@
+package medego
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/PrinMeshia/medego/render"
+)
+
+// formats served by Respond, by ?format= value, in order of preference when the client accepts any
+var responseFormats = []struct {
+	format    string
+	mediaType string
+}{
+	{"html", "text/html"},
+	{"json", "application/json"},
+	{"xml", "application/xml"},
+	{"xml", "text/xml"},
+}
+
+// Respond writes data in the format the client asks for with its Accept header, or with ?format=html|json|xml:
+// the view rendered as HTML for browsers, or data as JSON or XML for API clients. It writes 406 Not Acceptable
+// when no format matches. An empty view disables HTML.
+//
+// When data is *render.TemplateData the view gets it as is, and API clients get its Data map, as JSON
+// only since maps cannot be written as XML.
+// Any other value is available to the view as .Data.data, and as the data variable in jet templates.
+func (c *Medego) Respond(w http.ResponseWriter, r *http.Request, status int, view string, data interface{}) error {
+	w.Header().Add("Vary", "Accept")
+
+	payload := data
+	td, isTemplateData := data.(*render.TemplateData)
+	if isTemplateData {
+		payload = td.Data
+	}
+
+	format := c.negotiateFormat(r, view != "", true)
+	var xmlOut []byte
+	if format == "xml" {
+		// encoding/xml cannot marshal maps, such as the Data of template data: the next format
+		// the client accepts is served instead, if any
+		var err error
+		if xmlOut, err = xml.MarshalIndent(payload, "", " "); err != nil {
+			format = c.negotiateFormat(r, view != "", false)
+		}
+	}
+
+	switch format {
+	case "":
+		c.RenderError(w, r, http.StatusNotAcceptable, nil)
+		return nil
+	case "json":
+		return c.WriteJSON(w, status, payload)
+	case "xml":
+		c.setHeaders(w, status, "application/xml")
+		_, err := w.Write(xmlOut)
+		return err
+	}
+
+	vars := make(jet.VarMap)
+	if !isTemplateData {
+		td = &render.TemplateData{Data: map[string]interface{}{"data": data}}
+		vars.Set("data", data)
+	}
+
+	// render to a buffer, so that a failing view can still be answered with an error page
+	buf := new(bytes.Buffer)
+	if err := c.Render.Execute(buf, r, view, vars, td); err != nil {
+		return err
+	}
+
+	c.setHeaders(w, status, "text/html; charset=utf-8")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// negotiateFormat returns the format of the response, html, json or xml, or an empty string when
+// the client accepts none of those offered
+func (c *Medego) negotiateFormat(r *http.Request, html, xmlOK bool) string {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		switch {
+		case format == "html" && html, format == "json", format == "xml" && xmlOK:
+			return format
+		default:
+			return ""
+		}
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		if html {
+			return "html"
+		}
+		return "json"
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ, bestLevel := "", 0.0, -1
+	for _, offer := range responseFormats {
+		if (offer.format == "html" && !html) || (offer.format == "xml" && !xmlOK) {
+			continue
+		}
+
+		for _, mr := range ranges {
+			level, ok := mr.matches(offer.mediaType)
+			if !ok {
+				continue
+			}
+			// the most specific range applies, q=0 refusing the type, and offers keep their order on ties
+			if mr.q > 0 && (mr.q > bestQ || (mr.q == bestQ && level > bestLevel)) {
+				best, bestQ, bestLevel = offer.format, mr.q, level
+			}
+			break
+		}
+	}
+	return best
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// matches reports whether the media range covers a media type, and how specifically:
+// 2 for type/subtype, 1 for type/* and 0 for */*
+func (mr mediaRange) matches(mediaType string) (int, bool) {
+	switch {
+	case mr.mediaType == mediaType:
+		return 2, true
+	case mr.mediaType == "*/*":
+		return 0, true
+	case strings.HasSuffix(mr.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mr.mediaType, "*")):
+		return 1, true
+	default:
+		return 0, false
+	}
+}
+
+// parseAccept parses an Accept header, e.g. text/html,application/json;q=0.9,*/*;q=0.1, into media
+// ranges sorted from the most to the least specific
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mr := mediaRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		if mr.mediaType == "" {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					mr.q = q
+				}
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+	return ranges
+}
+
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,93 @@
+package medego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/PrinMeshia/medego/render"
+	"github.com/alexedwards/scs/v2"
+)
+
+type greeting struct {
+	Name string `json:"name" xml:"name"`
+}
+
+// testApp returns an app rendering Go templates from memory
+func testApp() *Medego {
+	session := scs.New()
+	return &Medego{
+		Session: session,
+		Render: &render.Render{
+			Renderer: "go",
+			Session:  session,
+			FS: fstest.MapFS{
+				"greeting.page.tmpl": {Data: []byte(`<p>Hello {{with index .Data "data"}}{{.Name}}{{else}}{{index .Data "name"}}{{end}}</p>`)},
+			},
+		},
+	}
+}
+
+func TestMedego_Respond(t *testing.T) {
+	app := testApp()
+
+	var tests = []struct {
+		name        string
+		accept      string
+		query       string
+		view        string
+		data        interface{}
+		status      int
+		contentType string
+		expected    string
+	}{
+		{"browser", "text/html,application/xhtml+xml,*/*;q=0.8", "", "greeting", greeting{"Ann"}, http.StatusOK, "text/html; charset=utf-8", "<p>Hello Ann</p>"},
+		{"no accept header", "", "", "greeting", greeting{"Ann"}, http.StatusOK, "text/html; charset=utf-8", "<p>Hello Ann</p>"},
+		{"template data", "text/html", "", "greeting", &render.TemplateData{Data: map[string]interface{}{"name": "Bob"}}, http.StatusOK, "text/html; charset=utf-8", "<p>Hello Bob</p>"},
+		{"json", "application/json", "", "greeting", greeting{"Ann"}, http.StatusOK, "application/json", `"name": "Ann"`},
+		{"json of template data", "application/json", "", "greeting", &render.TemplateData{Data: map[string]interface{}{"name": "Bob"}}, http.StatusOK, "application/json", `"name": "Bob"`},
+		{"xml", "application/xml", "", "greeting", greeting{"Ann"}, http.StatusOK, "application/xml", "<name>Ann</name>"},
+		{"format parameter", "text/html", "format=xml", "greeting", greeting{"Ann"}, http.StatusOK, "application/xml", "<name>Ann</name>"},
+		{"preferred format", "application/json;q=0.5, application/xml", "", "greeting", greeting{"Ann"}, http.StatusOK, "application/xml", "<name>Ann</name>"},
+		{"no view", "text/html, application/json;q=0.1", "", "", greeting{"Ann"}, http.StatusOK, "application/json", `"name": "Ann"`},
+		{"map as xml falls back", "application/xml, application/json;q=0.5", "", "greeting", &render.TemplateData{Data: map[string]interface{}{"name": "Bob"}}, http.StatusOK, "application/json", `"name": "Bob"`},
+		{"map as xml only", "application/xml", "", "greeting", &render.TemplateData{Data: map[string]interface{}{"name": "Bob"}}, http.StatusNotAcceptable, "", ""},
+		{"map as xml format", "", "format=xml", "greeting", map[string]string{"name": "Bob"}, http.StatusNotAcceptable, "", ""},
+		{"unknown format", "", "format=csv", "greeting", greeting{"Ann"}, http.StatusNotAcceptable, "", ""},
+		{"no html without view", "", "format=html", "", greeting{"Ann"}, http.StatusNotAcceptable, "", ""},
+		{"refused types", "text/csv, application/json;q=0", "", "", greeting{"Ann"}, http.StatusNotAcceptable, "", ""},
+	}
+
+	for _, e := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/greeting?"+e.query, nil)
+		if e.accept != "" {
+			r.Header.Set("Accept", e.accept)
+		}
+		ctx, err := app.Session.Load(r.Context(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+
+		if err := app.Respond(rr, r.WithContext(ctx), http.StatusOK, e.view, e.data); err != nil {
+			t.Errorf("%s: %v", e.name, err)
+			continue
+		}
+
+		if rr.Code != e.status {
+			t.Errorf("%s: expected status %d, got %d", e.name, e.status, rr.Code)
+			continue
+		}
+		if e.contentType != "" && rr.Header().Get("Content-Type") != e.contentType {
+			t.Errorf("%s: expected content type %s, got %s", e.name, e.contentType, rr.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(rr.Body.String(), e.expected) {
+			t.Errorf("%s: expected the body to contain %q, got %s", e.name, e.expected, rr.Body.String())
+		}
+		if rr.Header().Get("Vary") != "Accept" {
+			t.Errorf("%s: expected Vary: Accept, got %q", e.name, rr.Header().Get("Vary"))
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package pubsub
+
+// Memory is a hub delivering messages to the subscribers of the same process
+type Memory struct {
+	registry *registry
+}
+
+// NewMemory returns an in-process hub
+func NewMemory() *Memory {
+	return &Memory{registry: newRegistry()}
+}
+
+// Publish sends data to the subscribers of a topic
+func (m *Memory) Publish(topic string, data []byte) error {
+	m.registry.mu.RLock()
+	closed := m.registry.closed
+	m.registry.mu.RUnlock()
+	if closed {
+		return ErrClosed
+	}
+
+	m.registry.deliver(Message{Topic: topic, Data: data})
+	return nil
+}
+
+// Subscribe returns a subscription receiving the messages of the topics
+func (m *Memory) Subscribe(topics ...string) (*Subscription, error) {
+	sub, _, err := m.registry.add(topics)
+	return sub, err
+}
+
+// Close ends every subscription
+func (m *Memory) Close() error {
+	m.registry.close()
+	return nil
+}
@@ -0,0 +1,152 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when publishing to, or subscribing on, a closed hub
+var ErrClosed = errors.New("pubsub: hub closed")
+
+// subscriptionBuffer is the number of messages a subscriber may lag behind; later messages are dropped
+const subscriptionBuffer = 64
+
+// Message is the data published on a topic, e.g. users.42.notifications
+type Message struct {
+	Topic string
+	Data  []byte
+}
+
+// Hub delivers the messages published on topics to their subscribers
+type Hub interface {
+	// Publish sends data to the subscribers of a topic
+	Publish(topic string, data []byte) error
+	// Subscribe returns a subscription receiving the messages of the topics
+	Subscribe(topics ...string) (*Subscription, error)
+	// Close ends every subscription
+	Close() error
+}
+
+// Subscription receives the messages of its topics on C, until it is closed
+type Subscription struct {
+	C <-chan Message
+
+	ch       chan Message
+	topics   []string
+	registry *registry
+	once     sync.Once
+}
+
+// Close ends the subscription and closes C
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.registry.remove(s)
+	})
+}
+
+// registry holds the subscriptions of a hub by topic, and delivers them the messages
+type registry struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]struct{}
+	closed bool
+	// unsubscribe is called with the topics left without subscriber
+	unsubscribe func(topics []string)
+}
+
+func newRegistry() *registry {
+	return &registry{topics: make(map[string]map[*Subscription]struct{})}
+}
+
+// add returns a subscription to the topics, and the topics that had no subscriber
+func (r *registry) add(topics []string) (*Subscription, []string, error) {
+	ch := make(chan Message, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, topics: topics, registry: r}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, nil, ErrClosed
+	}
+
+	var added []string
+	for _, topic := range topics {
+		if r.topics[topic] == nil {
+			r.topics[topic] = make(map[*Subscription]struct{})
+			added = append(added, topic)
+		}
+		r.topics[topic][sub] = struct{}{}
+	}
+	return sub, added, nil
+}
+
+func (r *registry) remove(sub *Subscription) {
+	r.mu.Lock()
+
+	var removed []string
+	for _, topic := range sub.topics {
+		subs, ok := r.topics[topic]
+		if !ok {
+			continue
+		}
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(r.topics, topic)
+			removed = append(removed, topic)
+		}
+	}
+	// the channel is closed under the lock, so that deliver never sends on it afterwards
+	close(sub.ch)
+	r.mu.Unlock()
+
+	if len(removed) > 0 && r.unsubscribe != nil {
+		r.unsubscribe(removed)
+	}
+}
+
+// deliver sends a message to the subscribers of its topic, dropping it for those lagging behind
+func (r *registry) deliver(msg Message) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for sub := range r.topics[msg.Topic] {
+		select {
+		case sub.ch <- msg:
+		default:
+		}
+	}
+}
+
+// subscribed returns the topics having subscribers
+func (r *registry) subscribed() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	topics := make([]string, 0, len(r.topics))
+	for topic := range r.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// close ends every subscription
+func (r *registry) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.closed = true
+
+	closed := make(map[*Subscription]struct{})
+	for _, subs := range r.topics {
+		for sub := range subs {
+			if _, ok := closed[sub]; !ok {
+				sub.once.Do(func() { close(sub.ch) })
+				closed[sub] = struct{}{}
+			}
+		}
+	}
+	r.topics = make(map[string]map[*Subscription]struct{})
+}
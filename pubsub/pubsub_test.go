@@ -0,0 +1,99 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func testHubs(t *testing.T) map[string]func() Hub {
+	s := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", s.Addr())
+		},
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+
+	return map[string]func() Hub{
+		"memory": func() Hub { return NewMemory() },
+		"redis":  func() Hub { return NewRedis(pool, "test-medego") },
+	}
+}
+
+func receive(t *testing.T, sub *Subscription) (Message, bool) {
+	t.Helper()
+	select {
+	case msg, ok := <-sub.C:
+		return msg, ok
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for a message")
+		return Message{}, false
+	}
+}
+
+func TestHub_PublishSubscribe(t *testing.T) {
+	for name, newHub := range testHubs(t) {
+		hub := newHub()
+
+		sub, err := hub.Subscribe("users.1", "users.2")
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		other, err := hub.Subscribe("users.2")
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		for _, topic := range []string{"users.3", "users.1", "users.2"} {
+			if err := hub.Publish(topic, []byte("hello "+topic)); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+
+		for _, expected := range []string{"users.1", "users.2"} {
+			if msg, _ := receive(t, sub); msg.Topic != expected || string(msg.Data) != "hello "+expected {
+				t.Errorf("%s: expected the message of %s, got %+v", name, expected, msg)
+			}
+		}
+		if msg, _ := receive(t, other); msg.Topic != "users.2" {
+			t.Errorf("%s: expected the message of users.2, got %+v", name, msg)
+		}
+
+		sub.Close()
+		if _, ok := receive(t, sub); ok {
+			t.Errorf("%s: expected a closed subscription to close its channel", name)
+		}
+
+		if err := hub.Close(); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if _, ok := receive(t, other); ok {
+			t.Errorf("%s: expected closing the hub to close the subscriptions", name)
+		}
+		if err := hub.Publish("users.1", nil); err != ErrClosed {
+			t.Errorf("%s: expected ErrClosed publishing on a closed hub, got %v", name, err)
+		}
+	}
+}
+
+func TestRedis_Instances(t *testing.T) {
+	hubs := testHubs(t)
+	first, second := hubs["redis"](), hubs["redis"]()
+	defer first.Close()
+	defer second.Close()
+
+	sub, err := second.Subscribe("jobs.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.Publish("jobs.7", []byte("50%")); err != nil {
+		t.Fatal(err)
+	}
+	if msg, _ := receive(t, sub); string(msg.Data) != "50%" {
+		t.Errorf("expected the message published by another instance, got %+v", msg)
+	}
+}
@@ -0,0 +1,226 @@
+package pubsub
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	redisReconnectDelay  = time.Second
+	redisSubscribeWait   = 5 * time.Second
+	redisChannelTemplate = "pubsub:"
+)
+
+// Redis is a hub relaying messages through Redis pub/sub, so that the messages published by any
+// instance of the application reach the subscribers of every instance
+type Redis struct {
+	Pool   *redis.Pool
+	Prefix string
+
+	registry *registry
+	start    sync.Once
+	done     chan struct{}
+
+	// mu guards the connection receiving the messages, and the subscriptions waiting for redis
+	mu      sync.Mutex
+	psc     *redis.PubSubConn
+	pending map[string][]chan struct{}
+}
+
+// NewRedis returns a hub using the connections of pool, e.g. the pool of the redis cache.
+// Channels are named <prefix>:pubsub:<topic>.
+func NewRedis(pool *redis.Pool, prefix string) *Redis {
+	h := &Redis{
+		Pool:     pool,
+		Prefix:   prefix,
+		registry: newRegistry(),
+		done:     make(chan struct{}),
+		pending:  make(map[string][]chan struct{}),
+	}
+	h.registry.unsubscribe = h.unsubscribe
+	return h
+}
+
+// Publish sends data to the subscribers of a topic on every instance
+func (h *Redis) Publish(topic string, data []byte) error {
+	if h.closed() {
+		return ErrClosed
+	}
+
+	conn := h.Pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("PUBLISH", h.channel(topic), data)
+	return err
+}
+
+// Subscribe returns a subscription receiving the messages of the topics, once redis has
+// confirmed the subscription of the topics
+func (h *Redis) Subscribe(topics ...string) (*Subscription, error) {
+	sub, added, err := h.registry.add(topics)
+	if err != nil {
+		return nil, err
+	}
+	h.start.Do(func() { go h.listen() })
+
+	if len(added) == 0 {
+		return sub, nil
+	}
+
+	channels := make([]interface{}, len(added))
+	confirmations := make([]chan struct{}, len(added))
+	h.mu.Lock()
+	for n, topic := range added {
+		channels[n] = h.channel(topic)
+		confirmations[n] = make(chan struct{})
+		h.pending[h.channel(topic)] = append(h.pending[h.channel(topic)], confirmations[n])
+	}
+	if h.psc != nil {
+		// on failure the listener reconnects, and subscribes every topic again
+		_ = h.psc.Subscribe(channels...)
+	}
+	h.mu.Unlock()
+
+	timeout := time.NewTimer(redisSubscribeWait)
+	defer timeout.Stop()
+	for _, confirmed := range confirmations {
+		select {
+		case <-confirmed:
+		case <-timeout.C:
+			sub.Close()
+			return nil, errors.New("pubsub: timeout subscribing to redis")
+		case <-h.done:
+			return nil, ErrClosed
+		}
+	}
+	return sub, nil
+}
+
+// Close ends every subscription, and the connection receiving the messages
+func (h *Redis) Close() error {
+	if h.closed() {
+		return nil
+	}
+	close(h.done)
+
+	h.registry.close()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.psc != nil {
+		// the listener closes the connection once redis has unsubscribed every channel, as
+		// closing it here would read its replies concurrently
+		return h.psc.Unsubscribe()
+	}
+	return nil
+}
+
+// listen receives the messages of redis and delivers them to the subscribers, reconnecting
+// when the connection fails
+func (h *Redis) listen() {
+	for {
+		h.receive()
+
+		select {
+		case <-h.done:
+			return
+		case <-time.After(redisReconnectDelay):
+		}
+	}
+}
+
+func (h *Redis) receive() {
+	psc := &redis.PubSubConn{Conn: h.Pool.Get()}
+	defer psc.Close()
+
+	h.mu.Lock()
+	if h.closed() {
+		h.mu.Unlock()
+		return
+	}
+	h.psc = psc
+	if topics := h.registry.subscribed(); len(topics) > 0 {
+		channels := make([]interface{}, len(topics))
+		for n, topic := range topics {
+			channels[n] = h.channel(topic)
+		}
+		if err := psc.Subscribe(channels...); err != nil {
+			h.psc = nil
+			h.mu.Unlock()
+			return
+		}
+	}
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		h.psc = nil
+		h.mu.Unlock()
+	}()
+
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			h.registry.deliver(Message{Topic: h.topic(v.Channel), Data: v.Data})
+		case redis.Subscription:
+			if v.Kind == "subscribe" {
+				h.confirm(v.Channel)
+			}
+			if v.Count == 0 && h.closed() {
+				return
+			}
+		case error:
+			return
+		}
+	}
+}
+
+func (h *Redis) closed() bool {
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// confirm releases the subscriptions waiting for a channel
+func (h *Redis) confirm(channel string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, confirmed := range h.pending[channel] {
+		close(confirmed)
+	}
+	delete(h.pending, channel)
+}
+
+// unsubscribe unsubscribes the topics left without subscriber
+func (h *Redis) unsubscribe(topics []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.psc == nil {
+		return
+	}
+	channels := make([]interface{}, len(topics))
+	for n, topic := range topics {
+		channels[n] = h.channel(topic)
+	}
+	_ = h.psc.Unsubscribe(channels...)
+}
+
+func (h *Redis) channel(topic string) string {
+	if h.Prefix == "" {
+		return redisChannelTemplate + topic
+	}
+	return h.Prefix + ":" + redisChannelTemplate + topic
+}
+
+func (h *Redis) topic(channel string) string {
+	return strings.TrimPrefix(channel, h.channel(""))
+}
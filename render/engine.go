@@ -0,0 +1,54 @@
+package render
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Engine renders a view with its variables and template data. Apps can register their
+// own engines with RegisterEngine and select them with the RENDERER setting.
+type Engine interface {
+	Render(w io.Writer, r *http.Request, view string, variables, data interface{}) error
+}
+
+// EngineFunc lets an ordinary function be used as an Engine
+type EngineFunc func(w io.Writer, r *http.Request, view string, variables, data interface{}) error
+
+// Render calls f(w, r, view, variables, data)
+func (f EngineFunc) Render(w io.Writer, r *http.Request, view string, variables, data interface{}) error {
+	return f(w, r, view, variables, data)
+}
+
+// RegisterEngine makes an engine available under a name, replacing the built-in go and jet engines
+// when registered under their names
+func (c *Render) RegisterEngine(name string, engine Engine) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.engines == nil {
+		c.engines = make(map[string]Engine)
+	}
+	c.engines[strings.ToLower(name)] = engine
+}
+
+// engine returns the engine registered under name, or the built-in engine of that name
+func (c *Render) engine(name string) (Engine, bool) {
+	c.mu.RLock()
+	engine, ok := c.engines[name]
+	c.mu.RUnlock()
+	if ok {
+		return engine, true
+	}
+
+	switch name {
+	case "go":
+		return EngineFunc(func(w io.Writer, r *http.Request, view string, variables, data interface{}) error {
+			return c.GoPage(w, r, view, data)
+		}), true
+	case "jet":
+		return EngineFunc(c.JetPage), true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,218 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/PrinMeshia/medego/i18n"
+	"github.com/gertd/go-pluralize"
+)
+
+var pluralizer = pluralize.NewClient()
+
+// AddFunc makes a function available to the templates of every engine, e.g.
+//
+//	c.Render.AddFunc("money", func(cents int) string { return fmt.Sprintf("%.2f", float64(cents)/100) })
+//
+// Functions may return a second error value. Functions returning template.HTML are written unescaped.
+func (c *Render) AddFunc(name string, fn interface{}) {
+	c.mu.Lock()
+	if c.funcs == nil {
+		c.funcs = make(template.FuncMap)
+	}
+	c.funcs[name] = fn
+	// templates parsed without the function must be parsed again
+	c.templateCache = nil
+	c.mu.Unlock()
+
+	if c.JetViews != nil {
+		c.JetViews.AddGlobal(name, jetFunc(fn))
+	}
+}
+
+// Funcs returns the built-in functions and the functions added with AddFunc
+func (c *Render) Funcs() template.FuncMap {
+	funcs := template.FuncMap{
+		"urlFor":     c.urlFor,
+		"asset":      c.asset,
+		"csrfField":  csrfField,
+		"formatDate": formatDate,
+		"pluralize":  pluralizeWord,
+		"dict":       dict,
+		// replaced by the translation function of the locale of each request
+		"t": c.translateFunc(nil),
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for name, fn := range c.funcs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// addJetFuncs adds the functions to the jet set, which may have been created after them
+func (c *Render) addJetFuncs() {
+	if c.JetViews == nil {
+		return
+	}
+	for name, fn := range c.Funcs() {
+		c.JetViews.AddGlobal(name, jetFunc(fn))
+	}
+}
+
+// translateFunc returns the t function of templates, translating a key in the locale of the request
+// with the Translator, e.g. {{t "welcome" "name" .Data.name}}
+func (c *Render) translateFunc(r *http.Request) func(key string, args ...interface{}) string {
+	locale := ""
+	if r != nil {
+		locale = i18n.Locale(r.Context())
+	}
+
+	return func(key string, args ...interface{}) string {
+		if c.Translator == nil {
+			return i18n.Format(key, args...)
+		}
+		return c.Translator.T(locale, key, args...)
+	}
+}
+
+// urlFor returns the absolute URL of a path, replacing its {name} placeholders with the
+// values given as name, value pairs, and adding the other pairs to the query string, e.g.
+// urlFor "/users/{id}" "id" 3 "tab" "posts" gives https://example.com/users/3?tab=posts
+func (c *Render) urlFor(path string, pairs ...interface{}) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", errors.New("urlFor expects name, value pairs")
+	}
+
+	query := url.Values{}
+	for i := 0; i < len(pairs); i += 2 {
+		name := fmt.Sprint(pairs[i])
+		value := fmt.Sprint(pairs[i+1])
+
+		placeholder := "{" + name + "}"
+		if strings.Contains(path, placeholder) {
+			path = strings.ReplaceAll(path, placeholder, url.PathEscape(value))
+		} else {
+			query.Add(name, value)
+		}
+	}
+
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return strings.TrimSuffix(c.URL, "/") + "/" + strings.TrimPrefix(path, "/"), nil
+}
+
+// asset returns the URL of a file of the public folder, fingerprinted with its content
+// when the files are served by Assets
+func (c *Render) asset(path string) string {
+	if c.Assets != nil {
+		return strings.TrimSuffix(c.URL, "/") + c.Assets.Path(path)
+	}
+	return strings.TrimSuffix(c.URL, "/") + "/public/" + strings.TrimPrefix(path, "/")
+}
+
+// csrfField returns the hidden input holding the CSRF token of a form
+func csrfField(token string) template.HTML {
+	return template.HTML(`<input type="hidden" name="csrf_token" value="` + template.HTMLEscapeString(token) + `">`)
+}
+
+// formatDate formats a time with a Go layout, 2006-01-02 by default
+func formatDate(t time.Time, layout ...string) string {
+	if t.IsZero() {
+		return ""
+	}
+	if len(layout) > 0 {
+		return t.Format(layout[0])
+	}
+	return t.Format("2006-01-02")
+}
+
+// pluralizeWord returns the word, in its plural form unless count is one, e.g. pluralize 3 "user" gives users.
+// The plural form may be given when it is irregular.
+func pluralizeWord(count int, word string, plural ...string) string {
+	if count == 1 || count == -1 {
+		return word
+	}
+	if len(plural) > 0 {
+		return plural[0]
+	}
+	return pluralizer.Plural(word)
+}
+
+// dict builds a map from key, value pairs, to pass several values to a partial
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict expects key, value pairs")
+	}
+
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %T", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	htmlType  = reflect.TypeOf(template.HTML(""))
+)
+
+// jetFunc adapts a template function to jet, which ignores a returned error and escapes
+// template.HTML: errors are raised as panics, which jet returns from Execute, and HTML is
+// written as is
+func jetFunc(fn interface{}) interface{} {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumOut() == 0 {
+		return fn
+	}
+
+	returnsError := t.NumOut() == 2 && t.Out(1) == errorType
+	returnsHTML := t.Out(0) == htmlType
+	if !returnsError && !returnsHTML {
+		return fn
+	}
+
+	in := make([]reflect.Type, t.NumIn())
+	for i := range in {
+		in[i] = t.In(i)
+	}
+	out := []reflect.Type{t.Out(0)}
+	if returnsHTML {
+		out[0] = reflect.TypeOf(jet.RendererFunc(nil))
+	}
+
+	return reflect.MakeFunc(reflect.FuncOf(in, out, t.IsVariadic()), func(args []reflect.Value) []reflect.Value {
+		var results []reflect.Value
+		if t.IsVariadic() {
+			results = v.CallSlice(args)
+		} else {
+			results = v.Call(args)
+		}
+
+		if returnsError && !results[1].IsNil() {
+			panic(results[1].Interface())
+		}
+
+		if returnsHTML {
+			html := results[0].String()
+			return []reflect.Value{reflect.ValueOf(jet.RendererFunc(func(r *jet.Runtime) {
+				_, _ = r.Writer.Write([]byte(html))
+			}))}
+		}
+		return results[:1]
+	}).Interface()
+}
@@ -0,0 +1,34 @@
+package render
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// FSLoader loads jet templates from a file system, such as an embed.FS holding the templates folder
+type FSLoader struct {
+	fsys fs.FS
+}
+
+// NewFSLoader returns a jet loader reading the templates of fsys
+func NewFSLoader(fsys fs.FS) *FSLoader {
+	return &FSLoader{fsys: fsys}
+}
+
+// Exists reports whether a template exists, jet giving paths such as /users/show.jet
+func (l *FSLoader) Exists(templatePath string) bool {
+	info, err := fs.Stat(l.fsys, fsPath(templatePath))
+	return err == nil && !info.IsDir()
+}
+
+// Open returns the content of a template
+func (l *FSLoader) Open(templatePath string) (io.ReadCloser, error) {
+	return l.fsys.Open(fsPath(templatePath))
+}
+
+// fsPath returns a slash separated path as the unrooted path fs.FS expects
+func fsPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -1,23 +1,30 @@
 package render
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"io/fs"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/CloudyKit/jet/v6"
+	"github.com/PrinMeshia/medego/i18n"
 	"github.com/justinas/nosurf"
 )
 
+const goPageSuffix = ".page.tmpl"
 
 func (c *Render) defaultData(td *TemplateData, r *http.Request) *TemplateData {
 	td.Secure = c.Secure
 	td.ServerName = c.ServerName
 	td.CSRFToken = nosurf.Token(r)
 	td.Port = c.Port
+	td.Locale = i18n.Locale(r.Context())
 	if c.Session.Exists(r.Context(), "userID") {
 		td.IsAuthenticated = true
 	}
@@ -26,54 +33,189 @@ func (c *Render) defaultData(td *TemplateData, r *http.Request) *TemplateData {
 	return td
 }
 
+// Data returns the template data of a page, with the defaults every page gets such as the CSRF token
+// and the flash message. It is meant for engines registered with RegisterEngine.
+func (c *Render) Data(r *http.Request, data interface{}) *TemplateData {
+	td := &TemplateData{}
+	if data != nil {
+		td = data.(*TemplateData)
+	}
+	return c.defaultData(td, r)
+}
+
+// Page renders a view with the engine named by Renderer
 func (c *Render) Page(w http.ResponseWriter, r *http.Request, view string, variables, data interface{}) error {
-	switch strings.ToLower(c.Renderer) {
-	case "go":
-		return c.GoPage(w, r, view, data)
-	case "jet":
-		return c.JetPage(w, r, view, variables, data)
-	default:
+	return c.Execute(w, r, view, variables, data)
+}
 
+// Execute renders a view with the engine named by Renderer to any writer, such as a buffer
+func (c *Render) Execute(w io.Writer, r *http.Request, view string, variables, data interface{}) error {
+	engine, ok := c.engine(strings.ToLower(c.Renderer))
+	if !ok {
+		return errors.New("no rendering engine specified")
 	}
-	return errors.New("no rendering engine specified")
+	return engine.Render(w, r, view, variables, data)
 }
 
-// GoPage renders a standard Go template
-func (c *Render) GoPage(w http.ResponseWriter, r *http.Request, view string, data interface{}) error {
-	tmpl, err := template.ParseFiles(fmt.Sprintf("%s/templates/%s.page.tmpl", c.RootPath, view))
+// GoPage renders a standard Go template, templates/<view>.page.tmpl, along with every layout and partial
+func (c *Render) GoPage(w io.Writer, r *http.Request, view string, data interface{}) error {
+	tmpl, err := c.goTemplate(view)
 	if err != nil {
 		return err
 	}
 
-	td := &TemplateData{}
-	if data != nil {
-		td = data.(*TemplateData)
+	// the cached template is cloned, as html/template cannot clone a template once executed,
+	// to give it the t function of the locale of the request
+	if tmpl, err = tmpl.Clone(); err != nil {
+		return err
+	}
+	tmpl.Funcs(template.FuncMap{"t": c.translateFunc(r)})
+
+	td := c.Data(r, data)
+
+	// render to a buffer first, so that a failing template does not send half a page
+	buf := new(bytes.Buffer)
+	if err = tmpl.ExecuteTemplate(buf, view+goPageSuffix, td); err != nil {
+		return err
 	}
 
-	err = tmpl.Execute(w, &td)
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// LoadTemplates parses every Go page template with the layouts and partials, and caches them.
+// Otherwise pages are parsed the first time they are rendered, and on each request in debug mode.
+// Functions must be added with AddFunc before the templates using them are parsed.
+func (c *Render) LoadTemplates() error {
+	pages, layouts, err := c.findGoTemplates()
 	if err != nil {
 		return err
 	}
 
+	funcs := c.Funcs()
+	cache := make(map[string]*template.Template, len(pages))
+	for view, page := range pages {
+		tmpl, err := parseGoTemplate(c.templates(), view, page, layouts, funcs)
+		if err != nil {
+			return err
+		}
+		cache[view] = tmpl
+	}
+
+	c.mu.Lock()
+	c.templateCache = cache
+	c.mu.Unlock()
 	return nil
 }
 
-// JetPage renders a template using the Jet templating engine
-func (c *Render) JetPage(w http.ResponseWriter, r *http.Request, templateName string, variables, data interface{}) error {
-	var vars jet.VarMap
+// goTemplate returns the parsed template of a page, from the cache unless in debug mode
+func (c *Render) goTemplate(view string) (*template.Template, error) {
+	if !c.Debug {
+		c.mu.RLock()
+		tmpl, ok := c.templateCache[view]
+		c.mu.RUnlock()
+		if ok {
+			return tmpl, nil
+		}
+	}
 
-	if variables == nil {
-		vars = make(jet.VarMap)
-	} else {
-		vars = variables.(jet.VarMap)
+	pages, layouts, err := c.findGoTemplates()
+	if err != nil {
+		return nil, err
 	}
 
-	td := &TemplateData{}
-	if data != nil {
-		td = data.(*TemplateData)
+	page, ok := pages[view]
+	if !ok {
+		return nil, fmt.Errorf("template %s%s not found", view, goPageSuffix)
+	}
+
+	tmpl, err := parseGoTemplate(c.templates(), view, page, layouts, c.Funcs())
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.Debug {
+		c.mu.Lock()
+		if c.templateCache == nil {
+			c.templateCache = make(map[string]*template.Template)
+		}
+		c.templateCache[view] = tmpl
+		c.mu.Unlock()
+	}
+	return tmpl, nil
+}
+
+// findGoTemplates returns the page templates by view name, e.g. users/show for
+// templates/users/show.page.tmpl, and the files of the layouts and partials
+func (c *Render) findGoTemplates() (map[string]string, []string, error) {
+	fsys := c.templates()
+	pages := make(map[string]string)
+	var layouts []string
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		switch {
+		case strings.HasSuffix(name, goPageSuffix):
+			pages[strings.TrimSuffix(name, goPageSuffix)] = name
+		case strings.HasSuffix(name, ".layout.tmpl"), strings.HasSuffix(name, ".partial.tmpl"):
+			layouts = append(layouts, name)
+		}
+		return nil
+	})
+	return pages, layouts, err
+}
+
+// templates returns the templates folder: FS, or else RootPath/templates on disk
+func (c *Render) templates() fs.FS {
+	if c.FS != nil {
+		return c.FS
+	}
+	return os.DirFS(c.RootPath + "/templates")
+}
+
+// parseGoTemplate parses a page and the layouts and partials it may use, under the name <view>.page.tmpl.
+// Layouts and partials are named by their path in the templates folder, e.g. admin/nav.partial.tmpl,
+// so that files of the same name in different folders do not replace each other.
+func parseGoTemplate(fsys fs.FS, view, page string, layouts []string, funcs template.FuncMap) (*template.Template, error) {
+	content, err := fs.ReadFile(fsys, page)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(view + goPageSuffix).Funcs(funcs).Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layout := range layouts {
+		content, err := fs.ReadFile(fsys, layout)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = tmpl.New(layout).Parse(string(content)); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+// JetPage renders a template using the Jet templating engine. The variables are copied to add
+// the t function of the locale of the request, so that a VarMap can be shared between requests.
+func (c *Render) JetPage(w io.Writer, r *http.Request, templateName string, variables, data interface{}) error {
+	c.jetFuncs.Do(c.addJetFuncs)
+
+	vars := make(jet.VarMap)
+	if variables != nil {
+		for name, value := range variables.(jet.VarMap) {
+			vars[name] = value
+		}
 	}
+	vars.Set("t", c.translateFunc(r))
 
-	td = c.defaultData(td, r)
+	td := c.Data(r, data)
 
 	t, err := c.JetViews.GetTemplate(fmt.Sprintf("%s.jet", templateName))
 	if err != nil {
@@ -1,9 +1,16 @@
 package render
 
 import (
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/PrinMeshia/medego/i18n"
 )
 
 var pageData = []struct {
@@ -26,6 +33,7 @@ func TestRender_Page(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
+		r = r.WithContext(getCtx(r))
 
 		w := httptest.NewRecorder()
 
@@ -51,6 +59,7 @@ func TestRender_GoPage(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	r = r.WithContext(getCtx(r))
 
 	testRenderer.Renderer = "go"
 	testRenderer.RootPath = "./testdata"
@@ -68,6 +77,7 @@ func TestRender_JetPage(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	r = r.WithContext(getCtx(r))
 
 	testRenderer.Renderer = "jet"
 
@@ -77,3 +87,236 @@ func TestRender_JetPage(t *testing.T) {
 	}
 
 }
+
+func TestRender_GoPageLayout(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest("GET", "/about", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	r = r.WithContext(getCtx(r))
+
+	testRenderer.Renderer = "go"
+	testRenderer.RootPath = "./testdata"
+
+	err = testRenderer.Page(w, r, "about", nil, nil)
+	if err != nil {
+		t.Fatal("Error rendering page", err)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{"<html>", "<nav>guest</nav>", "<p>About</p>", `name="csrf_token"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the rendered page, got %s", want, body)
+		}
+	}
+}
+
+func TestRender_LoadTemplates(t *testing.T) {
+	testRenderer.RootPath = "./testdata"
+	testRenderer.Debug = false
+
+	if err := testRenderer.LoadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, view := range []string{"home", "about"} {
+		if _, ok := testRenderer.templateCache[view]; !ok {
+			t.Errorf("expected %s to be cached", view)
+		}
+	}
+
+	if _, ok := testRenderer.templateCache["base"]; ok {
+		t.Error("layouts should not be cached as pages")
+	}
+}
+
+func TestRender_Funcs(t *testing.T) {
+	renderer := &Render{
+		RootPath: "./testdata/funcs",
+		URL:      "https://example.com",
+		JetViews: jet.NewSet(jet.NewOSFileSystemLoader("./testdata/funcs/templates"), jet.InDevelopmentMode()),
+		Session:  testRenderer.Session,
+	}
+	renderer.AddFunc("money", func(cents int) string {
+		return fmt.Sprintf("$%.2f", float64(cents)/100)
+	})
+
+	var tests = []struct {
+		renderer string
+		view     string
+		expected string
+	}{
+		{"go", "funcs", `$12.50 users Ada <input type="hidden" name="csrf_token" value="abc"> https://example.com/users/3?tab=posts`},
+		{"jet", "funcs", `$12.50 user <input type="hidden" name="csrf_token" value="abc"> https://example.com/public/css/app.css`},
+	}
+
+	for _, e := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r = r.WithContext(getCtx(r))
+		w := httptest.NewRecorder()
+
+		renderer.Renderer = e.renderer
+		if err := renderer.Page(w, r, e.view, nil, nil); err != nil {
+			t.Errorf("%s: %s", e.renderer, err)
+			continue
+		}
+
+		if got := strings.TrimSpace(w.Body.String()); got != e.expected {
+			t.Errorf("%s: expected %q, got %q", e.renderer, e.expected, got)
+		}
+	}
+}
+
+func TestRender_RegisterEngine(t *testing.T) {
+	renderer := &Render{Renderer: "text", Session: testRenderer.Session}
+	renderer.RegisterEngine("text", EngineFunc(func(w io.Writer, r *http.Request, view string, variables, data interface{}) error {
+		_, err := fmt.Fprintf(w, "view %s", view)
+		return err
+	}))
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	if err := renderer.Page(w, r, "home", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "view home" {
+		t.Errorf("expected the registered engine to render the page, got %q", w.Body.String())
+	}
+}
+
+func TestRender_Translate(t *testing.T) {
+	translations := i18n.New("en")
+	translations.Add("en", map[string]string{"welcome": "Welcome, {name}"})
+	translations.Add("fr", map[string]string{"welcome": "Bienvenue, {name}"})
+
+	renderer := &Render{
+		RootPath:   "./testdata/i18n",
+		JetViews:   jet.NewSet(jet.NewOSFileSystemLoader("./testdata/i18n/templates"), jet.InDevelopmentMode()),
+		Session:    testRenderer.Session,
+		Translator: translations,
+	}
+
+	var tests = []struct {
+		renderer string
+		locale   string
+		expected string
+	}{
+		{"go", "en", "Welcome, Ada"},
+		{"go", "fr", "Bienvenue, Ada"},
+		{"jet", "en", "Welcome, Ada"},
+		{"jet", "fr-CA", "Bienvenue, Ada"},
+	}
+
+	for _, e := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r = r.WithContext(i18n.WithLocale(getCtx(r), e.locale))
+		w := httptest.NewRecorder()
+
+		renderer.Renderer = e.renderer
+		if err := renderer.Page(w, r, "welcome", nil, nil); err != nil {
+			t.Errorf("%s %s: %s", e.renderer, e.locale, err)
+			continue
+		}
+
+		if got := strings.TrimSpace(w.Body.String()); got != e.expected {
+			t.Errorf("%s %s: expected %q, got %q", e.renderer, e.locale, e.expected, got)
+		}
+	}
+}
+
+func TestRender_GoPageFolders(t *testing.T) {
+	renderer := &Render{
+		Renderer: "go",
+		Session:  testRenderer.Session,
+		FS: fstest.MapFS{
+			"nav.partial.tmpl":       {Data: []byte(`home`)},
+			"admin/nav.partial.tmpl": {Data: []byte(`admin`)},
+			"site/nav.partial.tmpl":  {Data: []byte(`site`)},
+			"menu.page.tmpl":         {Data: []byte(`{{template "nav.partial.tmpl"}} {{template "admin/nav.partial.tmpl"}} {{template "site/nav.partial.tmpl"}}`)},
+		},
+	}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r = r.WithContext(getCtx(r))
+	w := httptest.NewRecorder()
+
+	if err := renderer.Page(w, r, "menu", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "home admin site"; w.Body.String() != expected {
+		t.Errorf("expected the partials of each folder %q, got %q", expected, w.Body.String())
+	}
+}
+
+func TestRender_JetPageSharedVars(t *testing.T) {
+	translations := i18n.New("en")
+	translations.Add("en", map[string]string{"welcome": "Welcome, {name}"})
+	translations.Add("fr", map[string]string{"welcome": "Bienvenue, {name}"})
+
+	renderer := &Render{
+		Renderer:   "jet",
+		RootPath:   "./testdata/i18n",
+		JetViews:   jet.NewSet(jet.NewOSFileSystemLoader("./testdata/i18n/templates"), jet.InDevelopmentMode()),
+		Session:    testRenderer.Session,
+		Translator: translations,
+	}
+
+	// the same variables render each request in its own locale, and are left unchanged
+	vars := make(jet.VarMap)
+	for _, e := range []struct{ locale, expected string }{{"fr", "Bienvenue, Ada"}, {"en", "Welcome, Ada"}} {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r = r.WithContext(i18n.WithLocale(getCtx(r), e.locale))
+		w := httptest.NewRecorder()
+
+		if err := renderer.Page(w, r, "welcome", vars, nil); err != nil {
+			t.Fatalf("%s: %s", e.locale, err)
+		}
+		if got := strings.TrimSpace(w.Body.String()); got != e.expected {
+			t.Errorf("%s: expected %q, got %q", e.locale, e.expected, got)
+		}
+	}
+	if len(vars) != 0 {
+		t.Errorf("expected the variables to be left unchanged, got %v", vars)
+	}
+}
+
+func TestRender_FS(t *testing.T) {
+	templates := fstest.MapFS{
+		"base.layout.tmpl":     {Data: []byte(`{{define "base"}}<main>{{block "content" .}}{{end}}</main>{{end}}`)},
+		"users/show.page.tmpl": {Data: []byte(`{{template "base" .}}{{define "content"}}go {{index .Data "name"}}{{end}}`)},
+		"users/show.jet":       {Data: []byte(`jet {{ .Data["name"] }}`)},
+	}
+
+	renderer := &Render{
+		FS:       templates,
+		JetViews: jet.NewSet(NewFSLoader(templates), jet.InDevelopmentMode()),
+		Session:  testRenderer.Session,
+	}
+
+	var tests = []struct {
+		renderer string
+		expected string
+	}{
+		{"go", "<main>go Ada</main>"},
+		{"jet", "jet Ada"},
+	}
+
+	for _, e := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r = r.WithContext(getCtx(r))
+		w := httptest.NewRecorder()
+
+		renderer.Renderer = e.renderer
+		td := &TemplateData{Data: map[string]interface{}{"name": "Ada"}}
+		if err := renderer.Page(w, r, "users/show", nil, td); err != nil {
+			t.Errorf("%s: %s", e.renderer, err)
+			continue
+		}
+
+		if got := strings.TrimSpace(w.Body.String()); got != e.expected {
+			t.Errorf("%s: expected %q, got %q", e.renderer, e.expected, got)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package render
 
 import (
+	"context"
+	"net/http"
 	"os"
 	"testing"
 
 	"github.com/CloudyKit/jet/v6"
+	"github.com/alexedwards/scs/v2"
 )
 
 var views = jet.NewSet(
@@ -16,8 +19,18 @@ var testRenderer = Render{
 	Renderer: "",
 	RootPath: "",
 	JetViews: views,
+	Session:  scs.New(),
 }
 
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
+
+// getCtx returns the context of a request with a loaded session, as the session middleware does
+func getCtx(r *http.Request) context.Context {
+	ctx, err := testRenderer.Session.Load(r.Context(), r.Header.Get("X-Session"))
+	if err != nil {
+		panic(err)
+	}
+	return ctx
+}
@@ -1,7 +1,13 @@
 package render
 
 import (
+	"html/template"
+	"io/fs"
+	"sync"
+
 	"github.com/CloudyKit/jet/v6"
+	"github.com/PrinMeshia/medego/i18n"
+	"github.com/PrinMeshia/medego/static"
 	"github.com/alexedwards/scs/v2"
 )
 
@@ -11,8 +17,20 @@ type Render struct {
 	Secure     bool
 	Port       string
 	ServerName string
+	URL        string
+	Debug      bool
 	JetViews   *jet.Set
+	// FS holds the templates folder, e.g. an embed.FS; when nil, templates are read from RootPath/templates
+	FS         fs.FS
 	Session    *scs.SessionManager
+	Translator i18n.Translator
+	Assets     *static.Server
+
+	mu            sync.RWMutex
+	templateCache map[string]*template.Template
+	engines       map[string]Engine
+	funcs         template.FuncMap
+	jetFuncs      sync.Once
 }
 
 type TemplateData struct {
@@ -27,4 +45,5 @@ type TemplateData struct {
 	Secure          bool
 	Error           string
 	Flash           string
+	Locale          string
 }
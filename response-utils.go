@@ -9,6 +9,10 @@ import (
 	"net/http"
 	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PrinMeshia/medego/model"
 )
 
 func (c *Medego) setHeaders(w http.ResponseWriter, status int, contentType string, headers ...http.Header) {
@@ -50,6 +54,40 @@ func (c *Medego) WriteJSON(w http.ResponseWriter, status int, data interface{},
 	return err
 }
 
+// WritePage writes a page of records as JSON, with a Link header pointing to the first,
+// previous, next and last pages, and the number of records in X-Total-Count
+func (c *Medego) WritePage(w http.ResponseWriter, r *http.Request, status int, page model.Paginated, headers ...http.Header) error {
+	meta := page.Pagination()
+
+	last := meta.TotalPages
+	if last == 0 {
+		last = 1
+	}
+
+	var links []string
+	link := func(n uint, rel string) {
+		u := *r.URL
+		query := u.Query()
+		query.Set("page", strconv.FormatUint(uint64(n), 10))
+		query.Set("per_page", strconv.FormatUint(uint64(meta.PerPage), 10))
+		u.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s%s>; rel="%s"`, c.Server.URL, u.RequestURI(), rel))
+	}
+
+	link(1, "first")
+	if meta.Page > 1 {
+		link(meta.Page-1, "prev")
+	}
+	if meta.Page < last {
+		link(meta.Page+1, "next")
+	}
+	link(last, "last")
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+	w.Header().Set("X-Total-Count", strconv.FormatUint(meta.Total, 10))
+	return c.WriteJSON(w, status, page, headers...)
+}
+
 func (c *Medego) WriteXML(w http.ResponseWriter, status int, data interface{}, headers ...http.Header) error {
 	out, err := xml.MarshalIndent(data, "", " ")
 	if err != nil {
@@ -69,19 +107,29 @@ func (c *Medego) DownloadFile(w http.ResponseWriter, r *http.Request, pathTolFil
 	return nil
 }
 
+// ErrorStatus answers with the problem details of the status, as application/problem+json.
+//
+// Deprecated: use RenderError, which answers browsers with the error page, or WriteError.
 func (c *Medego) ErrorStatus(w http.ResponseWriter, status int) {
-	http.Error(w, http.StatusText(status), status)
+	c.writeProblem(w, ProblemDetails{Type: "about:blank", Title: http.StatusText(status), Status: status})
 }
 
+// Error404 answers with the not found error page
 func (c *Medego) Error404(w http.ResponseWriter, r *http.Request) {
-	c.ErrorStatus(w, http.StatusNotFound)
+	c.RenderError(w, r, http.StatusNotFound, nil)
 }
+
+// Error500 answers with the internal server error page
 func (c *Medego) Error500(w http.ResponseWriter, r *http.Request) {
-	c.ErrorStatus(w, http.StatusInternalServerError)
+	c.RenderError(w, r, http.StatusInternalServerError, nil)
 }
+
+// ErrorUnauthorized answers with the unauthorized error page
 func (c *Medego) ErrorUnauthorized(w http.ResponseWriter, r *http.Request) {
-	c.ErrorStatus(w, http.StatusUnauthorized)
+	c.RenderError(w, r, http.StatusUnauthorized, nil)
 }
+
+// ErrorForbidden answers with the forbidden error page
 func (c *Medego) ErrorForbidden(w http.ResponseWriter, r *http.Request) {
-	c.ErrorStatus(w, http.StatusForbidden)
+	c.RenderError(w, r, http.StatusForbidden, nil)
 }
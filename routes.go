@@ -2,7 +2,9 @@ package medego
 
 import (
 	"net/http"
+	"strings"
 
+	"github.com/PrinMeshia/medego/mailer"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -14,8 +16,36 @@ func (c *Medego) routes() http.Handler {
 	if c.Debug {
 		mux.Use(middleware.Logger)
 	}
-	mux.Use(middleware.Recoverer)
+	if c.Assets != nil {
+		mux.Use(c.serveAssets)
+	}
 	mux.Use(c.SessionLoad)
+	mux.Use(c.Locale)
+	// after the session and the locale, which the error pages of the panics are rendered with
+	mux.Use(c.Recoverer)
 	mux.Use(c.NoSurf)
+
+	// the messages written by MAILER_API=file, and previews of the mail templates
+	if c.Debug && c.Mailer != nil {
+		mux.Get("/_mail", http.RedirectHandler("/_mail/", http.StatusMovedPermanently).ServeHTTP)
+		mux.Handle("/_mail/*", http.StripPrefix("/_mail", &mailer.Preview{Mail: c.Mailer}))
+	}
+
+	mux.NotFound(c.Error404)
+	mux.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		c.RenderError(w, r, http.StatusMethodNotAllowed, nil)
+	})
 	return mux
 }
+
+// serveAssets serves the requests under the prefix of the assets before the session, locale and
+// CSRF middlewares, so that static files cost no session lookup and set no cookie
+func (c *Medego) serveAssets(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, c.Assets.Prefix) {
+			c.Assets.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,52 @@
+package medego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/PrinMeshia/medego/static"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMedego_routes_Assets(t *testing.T) {
+	app := testApp()
+	app.Assets = static.New(fstest.MapFS{"app.css": {Data: []byte("body{}")}})
+	app.Assets.Debug = true
+	mux := app.routes().(*chi.Mux)
+	mux.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	var tests = []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"asset", "/public/app.css", http.StatusOK},
+		{"missing asset", "/public/missing.css", http.StatusNotFound},
+	}
+
+	for _, e := range tests {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, e.path, nil))
+
+		if rr.Code != e.status {
+			t.Errorf("%s: expected status %d, got %d", e.name, e.status, rr.Code)
+		}
+		// served before the session and CSRF middlewares
+		if cookies := rr.Result().Cookies(); len(cookies) != 0 {
+			t.Errorf("%s: expected no cookie, got %v", e.name, cookies)
+		}
+		if vary := rr.Header().Values("Vary"); slices.Contains(vary, "Cookie") {
+			t.Errorf("%s: expected not to vary with the cookies, got %v", e.name, vary)
+		}
+	}
+
+	// the other requests still go through them
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !slices.Contains(rr.Header().Values("Vary"), "Cookie") {
+		t.Error("expected the pages to vary with their cookies")
+	}
+}
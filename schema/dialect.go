@@ -0,0 +1,211 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ParseDialect returns the dialect for a DATABASE_TYPE value
+func ParseDialect(dbType string) (Dialect, error) {
+	switch strings.ToLower(dbType) {
+	case "postgres", "postgresql", "pgx":
+		return Postgres, nil
+	case "mysql", "mariadb":
+		return MySQL, nil
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	default:
+		return "", fmt.Errorf("unsupported database type %q", dbType)
+	}
+}
+
+func (d Dialect) quote(name string) string {
+	if d == MySQL {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+// columnType returns the SQL type of a model column, or an empty string when
+// its Go type has no known mapping
+func (d Dialect) columnType(col Column) string {
+	if col.Type != "" {
+		return col.Type
+	}
+
+	goType := strings.TrimPrefix(col.GoType, "*")
+	switch goType {
+	case "int", "int32", "uint", "uint32", "int16", "uint16", "int8", "uint8", "sql.NullInt32", "sql.NullInt16":
+		return map[Dialect]string{Postgres: "integer", MySQL: "int", SQLite: "INTEGER"}[d]
+	case "int64", "uint64", "sql.NullInt64":
+		return map[Dialect]string{Postgres: "bigint", MySQL: "bigint", SQLite: "INTEGER"}[d]
+	case "string", "sql.NullString":
+		return map[Dialect]string{Postgres: "character varying(255)", MySQL: "varchar(255)", SQLite: "TEXT"}[d]
+	case "bool", "sql.NullBool":
+		return map[Dialect]string{Postgres: "boolean", MySQL: "tinyint(1)", SQLite: "INTEGER"}[d]
+	case "float64", "float32", "sql.NullFloat64":
+		return map[Dialect]string{Postgres: "double precision", MySQL: "double", SQLite: "REAL"}[d]
+	case "time.Time", "sql.NullTime":
+		return map[Dialect]string{Postgres: "timestamp without time zone", MySQL: "timestamp", SQLite: "DATETIME"}[d]
+	case "[]byte":
+		return map[Dialect]string{Postgres: "bytea", MySQL: "blob", SQLite: "BLOB"}[d]
+	default:
+		return ""
+	}
+}
+
+// defaultValue returns the default used for a not null column of the given family,
+// or an empty string when the column should be nullable instead
+func (d Dialect) defaultValue(family string, adding bool) string {
+	switch family {
+	case "integer", "float":
+		return "0"
+	case "string":
+		return "''"
+	case "bool":
+		if d == Postgres {
+			return "false"
+		}
+		return "0"
+	case "time":
+		// sqlite refuses non constant defaults when adding a column
+		if d == SQLite && adding {
+			return ""
+		}
+		if d == Postgres {
+			return "now()"
+		}
+		return "CURRENT_TIMESTAMP"
+	default:
+		return ""
+	}
+}
+
+// columnDefinition returns the column name and type, with its constraints
+func (d Dialect) columnDefinition(col Column, adding bool) string {
+	if col.PrimaryKey {
+		switch d {
+		case Postgres:
+			if d.columnType(col) == "bigint" {
+				return d.quote(col.Name) + " BIGSERIAL PRIMARY KEY"
+			}
+			return d.quote(col.Name) + " SERIAL PRIMARY KEY"
+		case MySQL:
+			return d.quote(col.Name) + " " + d.columnType(col) + " unsigned NOT NULL AUTO_INCREMENT PRIMARY KEY"
+		default:
+			return d.quote(col.Name) + " INTEGER PRIMARY KEY AUTOINCREMENT"
+		}
+	}
+
+	sqlType := d.columnType(col)
+	if !col.Nullable {
+		if value := d.defaultValue(typeFamily(sqlType), adding); value != "" {
+			return d.quote(col.Name) + " " + sqlType + " NOT NULL DEFAULT " + value
+		}
+	}
+	return d.quote(col.Name) + " " + sqlType + " NULL"
+}
+
+func (d Dialect) createTable(t Table) string {
+	var defs []string
+	for _, col := range t.Columns {
+		if d.columnType(col) == "" {
+			continue
+		}
+		defs = append(defs, "    "+d.columnDefinition(col, false))
+	}
+
+	stmt := fmt.Sprintf("CREATE TABLE %s (\n%s\n)", d.quote(t.Name), strings.Join(defs, ",\n"))
+	if d == MySQL {
+		stmt += " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	}
+	return stmt + ";"
+}
+
+func (d Dialect) dropTable(table string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", d.quote(table))
+}
+
+func (d Dialect) addColumn(table string, col Column) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", d.quote(table), d.columnDefinition(col, true))
+}
+
+func (d Dialect) dropColumn(table, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", d.quote(table), d.quote(column))
+}
+
+func (d Dialect) changeColumnType(table, column, sqlType string) string {
+	switch d {
+	case Postgres:
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;",
+			d.quote(table), d.quote(column), sqlType, d.quote(column), sqlType)
+	case MySQL:
+		return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s;", d.quote(table), d.quote(column), sqlType)
+	default:
+		return fmt.Sprintf("-- sqlite cannot change the type of %s.%s to %s: the table must be rebuilt", table, column, sqlType)
+	}
+}
+
+func (d Dialect) createIndex(table, name string, col Column) string {
+	unique := ""
+	if col.Index == "unique" {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", unique, d.quote(name), d.quote(table), d.quote(col.Name))
+}
+
+func (d Dialect) dropIndex(table, name string) string {
+	if d == MySQL {
+		return fmt.Sprintf("DROP INDEX %s ON %s;", d.quote(name), d.quote(table))
+	}
+	return fmt.Sprintf("DROP INDEX IF EXISTS %s;", d.quote(name))
+}
+
+var typeLength = regexp.MustCompile(`\(.*\)`)
+
+// typeFamily groups SQL types whose values are interchangeable, so that
+// varchar(60) and text are not reported as a type change
+func typeFamily(sqlType string) string {
+	t := strings.ToLower(typeLength.ReplaceAllString(sqlType, ""))
+
+	switch {
+	case t == "tinyint" && strings.Contains(sqlType, "(1)"), t == "boolean", t == "bool":
+		return "bool"
+	case strings.Contains(t, "int"), strings.Contains(t, "serial"):
+		return "integer"
+	case strings.Contains(t, "char"), strings.Contains(t, "text"), strings.Contains(t, "clob"):
+		return "string"
+	case strings.Contains(t, "time"), strings.Contains(t, "date"):
+		return "time"
+	case strings.Contains(t, "double"), strings.Contains(t, "real"), strings.Contains(t, "float"),
+		strings.Contains(t, "numeric"), strings.Contains(t, "decimal"):
+		return "float"
+	case strings.Contains(t, "bytea"), strings.Contains(t, "blob"), strings.Contains(t, "binary"):
+		return "binary"
+	default:
+		return t
+	}
+}
+
+// typeChanged reports whether a live column type differs from the model one: another family,
+// or another length or precision when both types give one, e.g. varchar(255) and varchar(100).
+// The display width of integers is ignored.
+func typeChanged(current, wanted string) bool {
+	if typeFamily(current) != typeFamily(wanted) {
+		return true
+	}
+	if typeFamily(wanted) == "integer" {
+		return false
+	}
+
+	size := func(sqlType string) string {
+		return strings.ReplaceAll(typeLength.FindString(sqlType), " ", "")
+	}
+	return size(current) != "" && size(wanted) != "" && size(current) != size(wanted)
+}
+
+// indexName returns the name given to the index of a model column, e.g. users_email_unique
+func indexName(table string, col Column) string {
+	return table + "_" + col.Name + "_" + col.Index
+}
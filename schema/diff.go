@@ -0,0 +1,110 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Diff compares the model tables with the live schema and returns the statements that
+// create missing tables, columns and indexes and change column types and lengths. Tables
+// and columns that only exist in the database are left untouched.
+func Diff(models []Table, live map[string]*Table, d Dialect) Migration {
+	var m Migration
+	var down [][]string
+
+	for _, model := range models {
+		existing, ok := live[model.Name]
+		if !ok {
+			m.Up = append(m.Up, d.createTable(model))
+			for _, col := range model.Columns {
+				if d.columnType(col) == "" {
+					m.Up = append(m.Up, unsupportedColumn(model.Name, col))
+				}
+			}
+			// the indexes of a new table are dropped along with it
+			m.Up = append(m.Up, createIndexes(model, nil, d, nil)...)
+			down = append(down, []string{d.dropTable(model.Name)})
+			continue
+		}
+
+		for _, col := range model.Columns {
+			sqlType := d.columnType(col)
+			if sqlType == "" {
+				m.Up = append(m.Up, unsupportedColumn(model.Name, col))
+				continue
+			}
+
+			current, found := findColumn(existing.Columns, col.Name)
+			switch {
+			case !found:
+				m.Up = append(m.Up, d.addColumn(model.Name, col))
+				down = append(down, []string{d.dropColumn(model.Name, col.Name)})
+			case !col.PrimaryKey && typeChanged(current.Type, sqlType):
+				m.Up = append(m.Up, d.changeColumnType(model.Name, col.Name, sqlType))
+				down = append(down, []string{d.changeColumnType(model.Name, col.Name, current.Type)})
+			}
+		}
+
+		m.Up = append(m.Up, createIndexes(model, existing.Indexes, d, &down)...)
+	}
+
+	// undo in reverse order, so that indexes go before their columns and tables
+	for i := len(down) - 1; i >= 0; i-- {
+		m.Down = append(m.Down, down[i]...)
+	}
+	return m
+}
+
+// Empty reports whether the migration has no statement to run
+func (m Migration) Empty() bool {
+	for _, stmt := range m.Up {
+		if !strings.HasPrefix(stmt, "--") {
+			return false
+		}
+	}
+	return true
+}
+
+// UpSQL returns the up statements as the content of a migration file
+func (m Migration) UpSQL() string {
+	return strings.Join(m.Up, "\n\n") + "\n"
+}
+
+// DownSQL returns the down statements as the content of a migration file
+func (m Migration) DownSQL() string {
+	return strings.Join(m.Down, "\n\n") + "\n"
+}
+
+func createIndexes(model Table, existing []string, d Dialect, down *[][]string) []string {
+	var stmts []string
+	for _, col := range model.Columns {
+		if col.Index == "" || d.columnType(col) == "" {
+			continue
+		}
+
+		name := indexName(model.Name, col)
+		if slices.Contains(existing, name) {
+			continue
+		}
+
+		stmts = append(stmts, d.createIndex(model.Name, name, col))
+		if down != nil {
+			*down = append(*down, []string{d.dropIndex(model.Name, name)})
+		}
+	}
+	return stmts
+}
+
+func findColumn(columns []Column, name string) (Column, bool) {
+	for _, col := range columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return Column{}, false
+}
+
+func unsupportedColumn(table string, col Column) string {
+	return fmt.Sprintf("-- skipped %s.%s: no SQL type for %s, set one with a schema:\"type:...\" tag", table, col.Name, col.GoType)
+}
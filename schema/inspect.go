@@ -0,0 +1,129 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Inspect reads the tables, columns and indexes of the current database
+func Inspect(db *sql.DB, d Dialect) (map[string]*Table, error) {
+	tables := make(map[string]*Table)
+
+	table := func(name string) *Table {
+		if _, ok := tables[name]; !ok {
+			tables[name] = &Table{Name: name}
+		}
+		return tables[name]
+	}
+
+	switch d {
+	case Postgres, MySQL:
+		columnsQuery := `select table_name, column_name,
+			case when character_maximum_length is null then data_type
+			else data_type || '(' || character_maximum_length || ')' end, is_nullable
+			from information_schema.columns where table_schema = current_schema()
+			order by table_name, ordinal_position`
+		indexesQuery := `select tablename, indexname from pg_indexes where schemaname = current_schema()`
+
+		if d == MySQL {
+			columnsQuery = `select table_name, column_name, column_type, is_nullable
+				from information_schema.columns where table_schema = database()
+				order by table_name, ordinal_position`
+			indexesQuery = `select distinct table_name, index_name from information_schema.statistics
+				where table_schema = database()`
+		}
+
+		rows, err := db.Query(columnsQuery)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var tableName, nullable string
+			var col Column
+			if err := rows.Scan(&tableName, &col.Name, &col.Type, &nullable); err != nil {
+				return nil, err
+			}
+			col.Nullable = nullable == "YES"
+			t := table(tableName)
+			t.Columns = append(t.Columns, col)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		if err := inspectIndexes(db, indexesQuery, table); err != nil {
+			return nil, err
+		}
+
+	case SQLite:
+		rows, err := db.Query(`select name from sqlite_master where type = 'table' and name not like 'sqlite_%'`)
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			names = append(names, name)
+		}
+		rows.Close()
+
+		for _, name := range names {
+			if err := inspectSQLiteColumns(db, table(name)); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := inspectIndexes(db, `select tbl_name, name from sqlite_master where type = 'index'`, table); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported dialect %q", d)
+	}
+
+	return tables, nil
+}
+
+func inspectIndexes(db *sql.DB, query string, table func(string) *Table) error {
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, indexName string
+		if err := rows.Scan(&tableName, &indexName); err != nil {
+			return err
+		}
+		t := table(tableName)
+		t.Indexes = append(t.Indexes, indexName)
+	}
+	return rows.Err()
+}
+
+func inspectSQLiteColumns(db *sql.DB, t *Table) error {
+	rows, err := db.Query(`select name, type, "notnull" from pragma_table_info(?)`, t.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var col Column
+		var notNull int
+		if err := rows.Scan(&col.Name, &col.Type, &notNull); err != nil {
+			return err
+		}
+		col.Nullable = notNull == 0
+		t.Columns = append(t.Columns, col)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseModels reads the go files in dir and returns a table for every struct that has
+// a Table() method returning a string literal. Columns come from the fields' db tags;
+// a schema tag adds options, separated by semicolons:
+//
+//	Email string `db:"email" schema:"type:varchar(100);unique"`
+//
+// Supported options are type:<sql type>, index, unique, nullable and primary.
+func ParseModels(dir string) ([]Table, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	structs := make(map[string]*ast.StructType)
+	tableNames := make(map[string]string)
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				switch d := decl.(type) {
+				case *ast.GenDecl:
+					for _, spec := range d.Specs {
+						if ts, ok := spec.(*ast.TypeSpec); ok {
+							if st, ok := ts.Type.(*ast.StructType); ok {
+								structs[ts.Name.Name] = st
+							}
+						}
+					}
+				case *ast.FuncDecl:
+					if name, table, ok := tableMethod(d); ok {
+						tableNames[name] = table
+					}
+				}
+			}
+		}
+	}
+
+	var tables []Table
+	for name, table := range tableNames {
+		st, ok := structs[name]
+		if !ok {
+			continue
+		}
+		tables = append(tables, Table{Name: table, Columns: structColumns(st)})
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	return tables, nil
+}
+
+// tableMethod returns the receiver type and table name of a `Table() string { return "..." }` method
+func tableMethod(fn *ast.FuncDecl) (string, string, bool) {
+	if fn.Name.Name != "Table" || fn.Recv == nil || len(fn.Recv.List) != 1 || fn.Body == nil || len(fn.Body.List) != 1 {
+		return "", "", false
+	}
+
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	ident, ok := recv.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+
+	ret, ok := fn.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return "", "", false
+	}
+	lit, ok := ret.Results[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", "", false
+	}
+
+	table, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", "", false
+	}
+	return ident.Name, table, true
+}
+
+func structColumns(st *ast.StructType) []Column {
+	var columns []Column
+
+	for _, field := range st.Fields.List {
+		if field.Tag == nil || len(field.Names) == 0 {
+			continue
+		}
+
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+
+		dbTag, ok := reflect.StructTag(tag).Lookup("db")
+		if !ok {
+			continue
+		}
+
+		name, options, _ := strings.Cut(dbTag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		col := Column{
+			Name:   name,
+			GoType: exprString(field.Type),
+		}
+		col.Nullable = strings.HasPrefix(col.GoType, "*") || strings.HasPrefix(col.GoType, "sql.Null")
+		col.PrimaryKey = name == "id" && strings.Contains(options, "omitempty")
+
+		for _, opt := range strings.Split(reflect.StructTag(tag).Get("schema"), ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(opt), ":")
+			switch key {
+			case "type":
+				col.Type = value
+			case "index", "unique":
+				col.Index = key
+			case "nullable":
+				col.Nullable = true
+			case "primary":
+				col.PrimaryKey = true
+			}
+		}
+
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+// exprString renders a field type expression, such as *time.Time or []byte
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	case *ast.ArrayType:
+		return "[]" + exprString(e.Elt)
+	default:
+		return ""
+	}
+}
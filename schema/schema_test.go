@@ -0,0 +1,208 @@
+package schema
+
+import (
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSchema_ParseModels(t *testing.T) {
+	tables, err := ParseModels("./testdata/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tables) != 2 {
+		t.Fatalf("expected 2 tables, got %d", len(tables))
+	}
+
+	if tables[0].Name != "tokens" || tables[1].Name != "users" {
+		t.Errorf("unexpected tables %s and %s", tables[0].Name, tables[1].Name)
+	}
+
+	users := tables[1]
+	if len(users.Columns) != 7 {
+		t.Fatalf("expected 7 user columns, got %d", len(users.Columns))
+	}
+
+	if !users.Columns[0].PrimaryKey {
+		t.Error("id should be the primary key")
+	}
+
+	email, _ := findColumn(users.Columns, "email")
+	if email.Index != "unique" {
+		t.Error("email should have a unique index")
+	}
+
+	deletedAt, _ := findColumn(users.Columns, "deleted_at")
+	if !deletedAt.Nullable {
+		t.Error("pointer fields should be nullable")
+	}
+}
+
+var diffData = []struct {
+	name    string
+	dialect Dialect
+	up      []string
+	down    []string
+}{
+	{"postgres", Postgres,
+		[]string{
+			`ALTER TABLE "users" ADD COLUMN "email" character varying(255) NOT NULL DEFAULT '';`,
+			`ALTER TABLE "users" ALTER COLUMN "user_active" TYPE integer USING "user_active"::integer;`,
+			`ALTER TABLE "users" ADD COLUMN "score" double precision NOT NULL DEFAULT 0;`,
+			`ALTER TABLE "users" ADD COLUMN "deleted_at" timestamp without time zone NULL;`,
+			`CREATE UNIQUE INDEX "users_email_unique" ON "users" ("email");`,
+		},
+		[]string{
+			`DROP INDEX IF EXISTS "users_email_unique";`,
+			`ALTER TABLE "users" DROP COLUMN "deleted_at";`,
+			`ALTER TABLE "users" DROP COLUMN "score";`,
+			`ALTER TABLE "users" ALTER COLUMN "user_active" TYPE character varying(10) USING "user_active"::character varying(10);`,
+			`ALTER TABLE "users" DROP COLUMN "email";`,
+			`DROP TABLE IF EXISTS "tokens";`,
+		},
+	},
+	{"mysql", MySQL,
+		[]string{
+			"ALTER TABLE `users` ADD COLUMN `email` varchar(255) NOT NULL DEFAULT '';",
+			"ALTER TABLE `users` MODIFY COLUMN `user_active` int;",
+			"ALTER TABLE `users` ADD COLUMN `score` double NOT NULL DEFAULT 0;",
+			"ALTER TABLE `users` ADD COLUMN `deleted_at` timestamp NULL;",
+			"CREATE UNIQUE INDEX `users_email_unique` ON `users` (`email`);",
+		},
+		nil,
+	},
+}
+
+func TestSchema_Diff(t *testing.T) {
+	models, err := ParseModels("./testdata/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range diffData {
+		live := map[string]*Table{
+			"users": {
+				Name: "users",
+				Columns: []Column{
+					{Name: "id", Type: "integer"},
+					{Name: "first_name", Type: "text"},
+					{Name: "user_active", Type: "character varying(10)"},
+					{Name: "created_at", Type: "timestamp"},
+				},
+			},
+		}
+
+		m := Diff(models, live, e.dialect)
+
+		if len(m.Up) != len(e.up)+2 {
+			t.Fatalf("%s: expected %d up statements, got %d: %v", e.name, len(e.up)+2, len(m.Up), m.Up)
+		}
+
+		// the tokens table comes first, with its index
+		if m.Up[0] != e.dialect.createTable(models[0]) {
+			t.Errorf("%s: expected the tokens table to be created, got %s", e.name, m.Up[0])
+		}
+
+		if !slices.Equal(m.Up[2:], e.up) {
+			t.Errorf("%s: unexpected up statements %v", e.name, m.Up[2:])
+		}
+
+		if e.down != nil && !slices.Equal(m.Down, e.down) {
+			t.Errorf("%s: unexpected down statements %v", e.name, m.Down)
+		}
+	}
+}
+
+func TestSchema_DiffInSync(t *testing.T) {
+	models := []Table{{Name: "items", Columns: []Column{{Name: "id", GoType: "int", PrimaryKey: true}, {Name: "name", GoType: "string"}}}}
+	live := map[string]*Table{"items": {Name: "items", Columns: []Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "text"}}}}
+
+	if m := Diff(models, live, Postgres); !m.Empty() {
+		t.Errorf("expected no statements, got %v", m.Up)
+	}
+}
+
+func TestSchema_DiffLength(t *testing.T) {
+	models := []Table{{Name: "items", Columns: []Column{
+		{Name: "name", Type: "varchar(100)"},
+		{Name: "price", Type: "decimal(10,2)"},
+		{Name: "note", Type: "varchar(255)"},
+		{Name: "quantity", Type: "int"},
+	}}}
+	live := map[string]*Table{"items": {Name: "items", Columns: []Column{
+		{Name: "name", Type: "varchar(255)"},
+		{Name: "price", Type: "decimal(10, 2)"},
+		{Name: "note", Type: "text"},
+		{Name: "quantity", Type: "int(11)"},
+	}}}
+
+	m := Diff(models, live, MySQL)
+	expected := []string{"ALTER TABLE `items` MODIFY COLUMN `name` varchar(100);"}
+	if !slices.Equal(m.Up, expected) {
+		t.Errorf("expected only the length of name to change, got %v", m.Up)
+	}
+	if expected := []string{"ALTER TABLE `items` MODIFY COLUMN `name` varchar(255);"}; !slices.Equal(m.Down, expected) {
+		t.Errorf("expected the length to be restored, got %v", m.Down)
+	}
+}
+
+func TestSchema_InspectSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "schema.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		first_name TEXT NOT NULL,
+		user_active INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NULL
+	);
+	CREATE INDEX users_first_name_index ON users (first_name);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live, err := Inspect(db, SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, ok := live["users"]
+	if !ok || len(live) != 1 {
+		t.Fatalf("expected only the users table, got %v", live)
+	}
+	expected := []Column{
+		{Name: "id", Type: "INTEGER", Nullable: true},
+		{Name: "first_name", Type: "TEXT"},
+		{Name: "user_active", Type: "INTEGER"},
+		{Name: "created_at", Type: "DATETIME", Nullable: true},
+	}
+	if !slices.Equal(users.Columns, expected) {
+		t.Errorf("expected the columns %v, got %v", expected, users.Columns)
+	}
+	if !slices.Equal(users.Indexes, []string{"users_first_name_index"}) {
+		t.Errorf("expected the first_name index, got %v", users.Indexes)
+	}
+
+	// the inspected schema diffs against the models
+	models, err := ParseModels("./testdata/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Diff(models, live, SQLite)
+	if !slices.Contains(m.Up, `ALTER TABLE "users" ADD COLUMN "email" TEXT NOT NULL DEFAULT '';`) {
+		t.Errorf("expected the email column to be added, got %v", m.Up)
+	}
+	for _, stmt := range m.Up {
+		if stmt == `ALTER TABLE "users" ADD COLUMN "first_name" TEXT NOT NULL DEFAULT '';` {
+			t.Errorf("expected the existing first_name column to be kept, got %v", m.Up)
+		}
+	}
+}
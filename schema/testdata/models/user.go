@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type User struct {
+	ID        int        `db:"id,omitempty"`
+	FirstName string     `db:"first_name"`
+	Email     string     `db:"email" schema:"unique"`
+	Active    int        `db:"user_active"`
+	Score     float64    `db:"score"`
+	DeletedAt *time.Time `db:"deleted_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	Token     Token      `db:"-"`
+}
+
+func (u *User) Table() string {
+	return "users"
+}
+
+type Token struct {
+	ID     int    `db:"id,omitempty"`
+	UserID int    `db:"user_id" schema:"index"`
+	Hash   []byte `db:"token_hash"`
+}
+
+func (t *Token) Table() string {
+	return "tokens"
+}
+
+type notAModel struct {
+	Name string `db:"name"`
+}
@@ -0,0 +1,33 @@
+package schema
+
+// Dialect is the SQL flavour migrations are generated for
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+)
+
+// Column describes a table column, either declared by a model field or read from the database
+type Column struct {
+	Name       string
+	GoType     string
+	Type       string
+	Nullable   bool
+	PrimaryKey bool
+	Index      string
+}
+
+// Table describes a database table and the names of its indexes
+type Table struct {
+	Name    string
+	Columns []Column
+	Indexes []string
+}
+
+// Migration holds the statements generated by Diff
+type Migration struct {
+	Up   []string
+	Down []string
+}
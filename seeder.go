@@ -0,0 +1,116 @@
+package medego
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	seedsDirName   = "seeds"
+	defaultAppEnv  = "development"
+	seedFileSuffix = ".sql"
+)
+
+// SeederFunc populates the database using the running application
+type SeederFunc func(c *Medego) error
+
+type seeder struct {
+	name string
+	fn   SeederFunc
+	envs []string
+}
+
+var seeders []seeder
+
+// RegisterSeeder registers a Go seeder; when envs is empty the seeder runs in every environment
+func RegisterSeeder(name string, fn SeederFunc, envs ...string) {
+	seeders = append(seeders, seeder{name: name, fn: fn, envs: envs})
+}
+
+// Environment returns the application environment, as set by APP_ENV
+func (c *Medego) Environment() string {
+	if env := os.Getenv("APP_ENV"); env != "" {
+		return env
+	}
+	return defaultAppEnv
+}
+
+// Seed runs the SQL seeds found in the seeds directory, then the ones found in
+// seeds/<environment>, then the registered Go seeders for the current environment.
+// When name is not empty, only the seed with that name is run.
+func (c *Medego) Seed(name string) error {
+	if c.DB.Pool == nil {
+		return errors.New("no database connection available for seeding")
+	}
+
+	env := c.Environment()
+	found := false
+
+	for _, dir := range []string{seedsDirName, path.Join(seedsDirName, env)} {
+		files, err := c.seedFiles(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			seedName := strings.TrimSuffix(path.Base(file), seedFileSuffix)
+			if name != "" && name != seedName {
+				continue
+			}
+			found = true
+
+			if err := c.runSQLSeed(file); err != nil {
+				return fmt.Errorf("seed %s: %w", seedName, err)
+			}
+		}
+	}
+
+	for _, s := range seeders {
+		if name != "" && name != s.name {
+			continue
+		}
+		if len(s.envs) > 0 && !slices.Contains(s.envs, env) {
+			continue
+		}
+		found = true
+
+		if err := s.fn(c); err != nil {
+			return fmt.Errorf("seed %s: %w", s.name, err)
+		}
+	}
+
+	if name != "" && !found {
+		return fmt.Errorf("no seeder named %s for environment %s", name, env)
+	}
+	return nil
+}
+
+// seedFiles returns the sorted list of SQL seed files in dir, relative to the root path
+func (c *Medego) seedFiles(dir string) ([]string, error) {
+	files, err := fs.Glob(c.Files("."), path.Join(dir, "*"+seedFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (c *Medego) runSQLSeed(file string) error {
+	content, err := fs.ReadFile(c.Files("."), file)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(string(content)) == "" {
+		return nil
+	}
+
+	_, err = c.DB.Pool.Exec(string(content))
+	return err
+}
@@ -0,0 +1,144 @@
+package medego
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// seedApp returns an app on a new sqlite database, with SQL seeds logging their name in the seeds table
+func seedApp(t *testing.T) *Medego {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "seed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if _, err := db.Exec("CREATE TABLE seeds (name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := func(name string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte("INSERT INTO seeds (name) VALUES ('" + name + "');")}
+	}
+	saved := seeders
+	t.Cleanup(func() { seeders = saved })
+	seeders = nil
+
+	return &Medego{
+		DB: Database{DataType: "sqlite", Pool: db},
+		FS: fstest.MapFS{
+			"seeds/02_posts.sql":            seed("posts"),
+			"seeds/01_users.sql":            seed("users"),
+			"seeds/empty.sql":               {Data: []byte("\n")},
+			"seeds/readme.txt":              {Data: []byte("not a seed")},
+			"seeds/testing/03_fixtures.sql": seed("fixtures"),
+			"seeds/production/04_admin.sql": seed("admin"),
+		},
+	}
+}
+
+// seeded returns the names logged by the seeds, in the order they ran
+func seeded(t *testing.T, c *Medego) []string {
+	t.Helper()
+
+	rows, err := c.DB.Pool.Query("SELECT name FROM seeds ORDER BY rowid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func goSeeder(name string) SeederFunc {
+	return func(c *Medego) error {
+		_, err := c.DB.Pool.Exec("INSERT INTO seeds (name) VALUES (?)", name)
+		return err
+	}
+}
+
+func TestMedego_Seed(t *testing.T) {
+	t.Setenv("APP_ENV", "testing")
+	app := seedApp(t)
+	RegisterSeeder("go", goSeeder("go"))
+	RegisterSeeder("go-testing", goSeeder("go-testing"), "testing", "development")
+	RegisterSeeder("go-production", goSeeder("go-production"), "production")
+
+	if err := app.Seed(""); err != nil {
+		t.Fatal(err)
+	}
+
+	// the SQL seeds in order, then those of the environment, then the Go seeders of the environment
+	expected := []string{"users", "posts", "fixtures", "go", "go-testing"}
+	if names := seeded(t, app); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected the seeds %v, got %v", expected, names)
+	}
+}
+
+func TestMedego_Seed_Name(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+
+	var tests = []struct {
+		name     string
+		expected []string
+	}{
+		{"02_posts", []string{"posts"}},
+		{"04_admin", []string{"admin"}},
+		{"go", []string{"go"}},
+	}
+
+	for _, e := range tests {
+		app := seedApp(t)
+		RegisterSeeder("go", goSeeder("go"))
+
+		if err := app.Seed(e.name); err != nil {
+			t.Errorf("%s: %v", e.name, err)
+			continue
+		}
+		if names := seeded(t, app); !reflect.DeepEqual(names, e.expected) {
+			t.Errorf("%s: expected the seeds %v, got %v", e.name, e.expected, names)
+		}
+	}
+}
+
+func TestMedego_Seed_Errors(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	app := seedApp(t)
+	RegisterSeeder("go-testing", goSeeder("go-testing"), "testing")
+	RegisterSeeder("failing", func(c *Medego) error { return errors.New("boom") })
+
+	// a seed of another environment is not found
+	for _, name := range []string{"03_fixtures", "go-testing", "missing"} {
+		err := app.Seed(name)
+		if err == nil || !strings.Contains(err.Error(), "no seeder named "+name+" for environment production") {
+			t.Errorf("%s: expected no seeder to be found, got %v", name, err)
+		}
+	}
+
+	if err := app.Seed("failing"); err == nil || err.Error() != "seed failing: boom" {
+		t.Errorf("expected the error of the seeder, got %v", err)
+	}
+
+	if err := (&Medego{}).Seed(""); err == nil {
+		t.Error("expected an error without database")
+	}
+	if names := seeded(t, app); len(names) != 0 {
+		t.Errorf("expected nothing to be seeded, got %v", names)
+	}
+}
@@ -0,0 +1,315 @@
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PrinMeshia/medego/pubsub"
+)
+
+const (
+	defaultHeartbeat  = 15 * time.Second
+	defaultBufferSize = 100
+	clientBuffer      = 16
+)
+
+// ErrStreamingUnsupported is returned when the response writer cannot flush
+var ErrStreamingUnsupported = errors.New("sse: streaming unsupported")
+
+// Event is a server-sent event. ID is set by Publish when empty, so that reconnecting clients
+// get the events they missed.
+type Event struct {
+	ID    string        `json:"id,omitempty"`
+	Event string        `json:"event,omitempty"`
+	Data  string        `json:"data"`
+	Retry time.Duration `json:"retry,omitempty"`
+}
+
+// Broker streams the events published on topics to the clients, e.g.
+//
+//	mux.Get("/jobs/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+//		_ = app.SSE.Stream(w, r, "jobs."+chi.URLParam(r, "id"))
+//	})
+//	...
+//	app.SSE.Publish("jobs.42", sse.Event{Event: "progress", Data: "50"})
+//
+// Events go through the hub, so that they reach the clients of every instance. Each instance keeps
+// the last events of the topics its clients follow, to replay them after Last-Event-ID when a client
+// reconnects.
+type Broker struct {
+	Hub pubsub.Hub
+	// Heartbeat is the interval of the comments keeping idle connections open, 15 seconds by default
+	Heartbeat time.Duration
+	// BufferSize is the number of events of a topic kept for replay, 100 by default
+	BufferSize int
+
+	mu     sync.Mutex
+	topics map[string]*topic
+	nextID uint64
+}
+
+// topic holds the last events of a topic and its clients
+type topic struct {
+	events  []Event
+	clients map[chan Event]struct{}
+	sub     *pubsub.Subscription
+}
+
+// NewBroker returns a broker publishing events through hub
+func NewBroker(hub pubsub.Hub) *Broker {
+	return &Broker{
+		Hub:        hub,
+		Heartbeat:  defaultHeartbeat,
+		BufferSize: defaultBufferSize,
+		topics:     make(map[string]*topic),
+	}
+}
+
+// Publish sends an event to the clients of a topic on every instance
+func (b *Broker) Publish(topic string, e Event) error {
+	if e.ID == "" {
+		e.ID = strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(atomic.AddUint64(&b.nextID, 1), 36)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.Hub.Publish(topic, data)
+}
+
+// Stream sends the events of the topics to the client until it disconnects. The events published
+// after the Last-Event-ID header, or the lastEventId query parameter, are sent first. The writer is
+// flushed through http.NewResponseController, so that it may be wrapped, e.g. by a session middleware.
+func (b *Broker) Stream(w http.ResponseWriter, r *http.Request, topics ...string) error {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	client := make(chan Event, clientBuffer)
+	missed, err := b.join(client, topics, lastEventID)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return err
+	}
+	defer b.leave(client, topics)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// proxies such as nginx would buffer the events otherwise
+	w.Header().Set("X-Accel-Buffering", "no")
+	rc := http.NewResponseController(w)
+	// the write timeout of the server would end the stream
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.WriteHeader(http.StatusOK)
+
+	for _, e := range missed {
+		if err := writeEvent(w, e); err != nil {
+			return err
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			return ErrStreamingUnsupported
+		}
+		return err
+	}
+
+	heartbeat := time.NewTicker(b.heartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		case e := <-client:
+			if err := writeEvent(w, e); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Handler returns a handler streaming the events of the topics
+func (b *Broker) Handler(topics ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = b.Stream(w, r, topics...)
+	})
+}
+
+// Close stops listening to the topics of the hub
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for name, t := range b.topics {
+		t.sub.Close()
+		delete(b.topics, name)
+	}
+}
+
+// join adds a client to topics, and returns the events it missed since lastEventID. The topics
+// are subscribed on the hub with the first client, and stay subscribed to keep their last events.
+func (b *Broker) join(client chan Event, topics []string, lastEventID string) ([]Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.topics == nil {
+		b.topics = make(map[string]*topic)
+	}
+
+	var missed []Event
+	for _, name := range topics {
+		t, ok := b.topics[name]
+		if !ok {
+			sub, err := b.Hub.Subscribe(name)
+			if err != nil {
+				b.removeClient(client, topics)
+				return nil, err
+			}
+			t = &topic{clients: make(map[chan Event]struct{}), sub: sub}
+			b.topics[name] = t
+			go b.receive(t)
+		}
+
+		t.clients[client] = struct{}{}
+		if lastEventID != "" {
+			missed = append(missed, t.since(lastEventID)...)
+		}
+	}
+	return missed, nil
+}
+
+func (b *Broker) leave(client chan Event, topics []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeClient(client, topics)
+}
+
+func (b *Broker) removeClient(client chan Event, topics []string) {
+	for _, name := range topics {
+		if t, ok := b.topics[name]; ok {
+			delete(t.clients, client)
+		}
+	}
+}
+
+// receive keeps the events of a topic and sends them to its clients, skipping the clients lagging behind
+func (b *Broker) receive(t *topic) {
+	for msg := range t.sub.C {
+		var e Event
+		if err := json.Unmarshal(msg.Data, &e); err != nil {
+			continue
+		}
+
+		b.mu.Lock()
+		t.events = append(t.events, e)
+		if size := b.bufferSize(); len(t.events) > size {
+			t.events = t.events[len(t.events)-size:]
+		}
+		for client := range t.clients {
+			select {
+			case client <- e:
+			default:
+			}
+		}
+		b.mu.Unlock()
+	}
+
+	// the hub closed the subscription: the next client subscribes again
+	b.mu.Lock()
+	for name, kept := range b.topics {
+		if kept == t {
+			delete(b.topics, name)
+		}
+	}
+	b.mu.Unlock()
+}
+
+// since returns the events after the event with the ID. When the topic does not keep it, as it is
+// an event of another topic or an old one, the events published after it are returned, going by
+// the time in the IDs set by Publish, or else every event kept.
+func (t *topic) since(id string) []Event {
+	for n := len(t.events) - 1; n >= 0; n-- {
+		if t.events[n].ID == id {
+			return append([]Event(nil), t.events[n+1:]...)
+		}
+	}
+
+	published, ok := eventTime(id)
+	if !ok {
+		return append([]Event(nil), t.events...)
+	}
+
+	var events []Event
+	for _, e := range t.events {
+		if at, ok := eventTime(e.ID); !ok || at > published {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// eventTime returns the time of publication in an ID set by Publish
+func eventTime(id string) (int64, bool) {
+	at, _, found := strings.Cut(id, "-")
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(at, 36, 64)
+	return n, err == nil
+}
+
+func (b *Broker) heartbeat() time.Duration {
+	if b.Heartbeat <= 0 {
+		return defaultHeartbeat
+	}
+	return b.Heartbeat
+}
+
+func (b *Broker) bufferSize() int {
+	if b.BufferSize <= 0 {
+		return defaultBufferSize
+	}
+	return b.BufferSize
+}
+
+// writeEvent writes an event in the text/event-stream format, data spanning several lines
+func writeEvent(w http.ResponseWriter, e Event) error {
+	var sb strings.Builder
+	if e.ID != "" {
+		sb.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Event != "" {
+		sb.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry > 0 {
+		sb.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+
+	_, err := fmt.Fprint(w, sb.String())
+	return err
+}
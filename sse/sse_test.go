@@ -0,0 +1,171 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PrinMeshia/medego/pubsub"
+	"github.com/alexedwards/scs/v2"
+)
+
+// readEvents reads the events of a stream, and the heartbeats as events named heartbeat
+func readEvents(t *testing.T, scanner *bufio.Scanner, count int) []Event {
+	t.Helper()
+
+	events := make(chan []Event, 1)
+	go func() {
+		var read []Event
+		var e Event
+		for len(read) < count && scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				read = append(read, e)
+				e = Event{}
+			case strings.HasPrefix(line, ": "):
+				e.Event = "heartbeat"
+			case strings.HasPrefix(line, "id: "):
+				e.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				e.Event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				if e.Data != "" {
+					e.Data += "\n"
+				}
+				e.Data += strings.TrimPrefix(line, "data: ")
+			}
+		}
+		events <- read
+	}()
+
+	select {
+	case read := <-events:
+		return read
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout reading %d events", count)
+		return nil
+	}
+}
+
+func stream(t *testing.T, server *httptest.Server, lastEventID string) (*bufio.Scanner, func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if lastEventID != "" {
+		r.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream, got %s", ct)
+	}
+
+	return bufio.NewScanner(resp.Body), func() {
+		cancel()
+		resp.Body.Close()
+	}
+}
+
+// waitForClients waits until the topic has the number of clients
+func waitForClients(b *Broker, name string, count int) {
+	for i := 0; i < 200; i++ {
+		b.mu.Lock()
+		t, ok := b.topics[name]
+		n := 0
+		if ok {
+			n = len(t.clients)
+		}
+		b.mu.Unlock()
+		if n == count {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBroker_Stream(t *testing.T) {
+	broker := NewBroker(pubsub.NewMemory())
+	broker.Heartbeat = 50 * time.Millisecond
+	server := httptest.NewServer(broker.Handler("jobs.1"))
+	defer server.Close()
+
+	scanner, stop := stream(t, server, "")
+	waitForClients(broker, "jobs.1", 1)
+
+	_ = broker.Publish("jobs.1", Event{Event: "progress", Data: "50"})
+	_ = broker.Publish("jobs.2", Event{Event: "progress", Data: "other job"})
+	_ = broker.Publish("jobs.1", Event{Data: "line 1\nline 2"})
+
+	events := readEvents(t, scanner, 2)
+	if events[0].Event != "progress" || events[0].Data != "50" || events[0].ID == "" {
+		t.Errorf("expected the progress event with an ID, got %+v", events[0])
+	}
+	if events[1].Data != "line 1\nline 2" {
+		t.Errorf("expected the data of several lines, got %q", events[1].Data)
+	}
+
+	if heartbeat := readEvents(t, scanner, 1); heartbeat[0].Event != "heartbeat" {
+		t.Errorf("expected a heartbeat, got %+v", heartbeat[0])
+	}
+	stop()
+
+	waitForClients(broker, "jobs.1", 0)
+	_ = broker.Publish("jobs.1", Event{Data: "missed"})
+
+	// the client reconnects with the ID of the last event it got
+	scanner, stop = stream(t, server, events[0].ID)
+	defer stop()
+
+	replayed := readEvents(t, scanner, 2)
+	if replayed[0].Data != "line 1\nline 2" || replayed[1].Data != "missed" {
+		t.Errorf("expected the events after Last-Event-ID, got %+v", replayed)
+	}
+}
+
+func TestBroker_StreamSession(t *testing.T) {
+	broker := NewBroker(pubsub.NewMemory())
+	// the session middleware wraps the response writer, which does not implement http.Flusher
+	server := httptest.NewServer(scs.New().LoadAndSave(broker.Handler("jobs.1")))
+	defer server.Close()
+
+	scanner, stop := stream(t, server, "")
+	defer stop()
+	waitForClients(broker, "jobs.1", 1)
+
+	_ = broker.Publish("jobs.1", Event{Data: "through the session"})
+	if events := readEvents(t, scanner, 1); events[0].Data != "through the session" {
+		t.Errorf("expected the event to be streamed, got %+v", events[0])
+	}
+}
+
+func TestBroker_BufferSize(t *testing.T) {
+	broker := NewBroker(pubsub.NewMemory())
+	broker.BufferSize = 2
+	server := httptest.NewServer(broker.Handler("jobs.1"))
+	defer server.Close()
+
+	scanner, stop := stream(t, server, "")
+	waitForClients(broker, "jobs.1", 1)
+	for _, data := range []string{"1", "2", "3"} {
+		_ = broker.Publish("jobs.1", Event{Data: data})
+	}
+	readEvents(t, scanner, 3)
+	stop()
+
+	// an unknown ID replays every event kept
+	scanner, stop = stream(t, server, "unknown")
+	defer stop()
+
+	if replayed := readEvents(t, scanner, 2); replayed[0].Data != "2" || replayed[1].Data != "3" {
+		t.Errorf("expected the last 2 events, got %+v", replayed)
+	}
+}
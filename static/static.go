@@ -0,0 +1,341 @@
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// ManifestFile is the manifest of assets fingerprinted by a build tool, mapping the names of
+	// the files to their fingerprinted names, e.g. {"css/app.css": "css/app.4f9a2c1e.css"}
+	ManifestFile = "manifest.json"
+
+	immutable   = "public, max-age=31536000, immutable"
+	revalidate  = "public, no-cache"
+	hashLength  = 10
+	defaultPath = "/public/"
+)
+
+// Server serves the files of a folder, such as public/, under a URL prefix. Assets are referenced by
+// fingerprinted URLs such as /public/css/app.4f9a2c1e6b.css, which change with their content and are
+// cached forever by browsers; the other URLs are revalidated with their ETag.
+type Server struct {
+	FS     fs.FS
+	Prefix string
+	// Debug disables fingerprinting, so that edited assets are served at once
+	Debug bool
+
+	mu     sync.RWMutex
+	loaded bool
+	assets map[string]asset
+	// names of the files by fingerprinted name
+	fingerprinted map[string]string
+}
+
+type asset struct {
+	hash        string
+	fingerprint string
+}
+
+// New returns a server of the files of fsys, e.g. os.DirFS("public") or a sub tree of an embed.FS,
+// under /public/
+func New(fsys fs.FS) *Server {
+	return &Server{
+		FS:     fsys,
+		Prefix: defaultPath,
+	}
+}
+
+// Load hashes the files, and reads the manifest of the build tool when there is one. It is called
+// on first use; files changed afterwards keep their first hash until Load is called again. In debug
+// mode the files are served without fingerprints, so edited files need no reload.
+func (s *Server) Load() error {
+	assets := make(map[string]asset)
+	fingerprinted := make(map[string]string)
+
+	err := fs.WalkDir(s.FS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || hidden(name) || isVariant(s.FS, name) || name == ManifestFile {
+			return nil
+		}
+
+		hash, err := hashFile(s.FS, name)
+		if err != nil {
+			return err
+		}
+		a := asset{hash: hash, fingerprint: fingerprint(name, hash)}
+		assets[name] = a
+		fingerprinted[a.fingerprint] = name
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	manifest, err := readManifest(s.FS)
+	if err != nil {
+		return err
+	}
+	for name, built := range manifest {
+		// the built file already has a fingerprint of its own
+		if a, ok := assets[built]; ok {
+			assets[name] = asset{hash: a.hash, fingerprint: built}
+			fingerprinted[built] = built
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assets = assets
+	s.fingerprinted = fingerprinted
+	s.loaded = true
+	return nil
+}
+
+// Path returns the URL of a file, fingerprinted with its content, e.g. css/app.css gives
+// /public/css/app.4f9a2c1e6b.css. Unknown files, and every file in debug mode, keep their name.
+func (s *Server) Path(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if s.Debug {
+		return s.Prefix + name
+	}
+
+	if a, ok := s.lookup(name); ok {
+		return s.Prefix + a.fingerprint
+	}
+	return s.Prefix + name
+}
+
+// ServeHTTP serves a file, with its ETag and cache headers, supporting conditional and range
+// requests. The .br or .gz variant of the file is served instead when it exists and the client
+// accepts it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(r.URL.Path, s.Prefix)), "/")
+	if name == "" || hidden(name) || name == ManifestFile {
+		http.NotFound(w, r)
+		return
+	}
+
+	cacheControl := revalidate
+	if !s.Debug {
+		if original, ok := s.original(name); ok {
+			cacheControl = immutable
+			name = original
+		}
+	}
+
+	info, err := fs.Stat(s.FS, name)
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	file, encoding := name, ""
+	// ranges apply to the bytes served, which would be those of the compressed file
+	if r.Header.Get("Range") == "" {
+		file, encoding = s.variant(name, r.Header.Get("Accept-Encoding"))
+	}
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+		if info, err = fs.Stat(s.FS, file); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	content, err := open(s.FS, file)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("ETag", s.etag(name, encoding, info))
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
+
+// lookup returns the asset of a file, loading the assets on first use
+func (s *Server) lookup(name string) (asset, bool) {
+	if !s.isLoaded() {
+		if err := s.Load(); err != nil {
+			return asset{}, false
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.assets[name]
+	return a, ok
+}
+
+// original returns the name of the file of a fingerprinted name
+func (s *Server) original(name string) (string, bool) {
+	if !s.isLoaded() {
+		if err := s.Load(); err != nil {
+			return "", false
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	original, ok := s.fingerprinted[name]
+	return original, ok
+}
+
+func (s *Server) isLoaded() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loaded
+}
+
+// variant returns the pre-compressed variant of a file the client accepts, and its encoding
+func (s *Server) variant(name, acceptEncoding string) (string, string) {
+	for _, v := range []struct{ encoding, ext string }{{"br", ".br"}, {"gzip", ".gz"}} {
+		if !accepts(acceptEncoding, v.encoding) {
+			continue
+		}
+		if info, err := fs.Stat(s.FS, name+v.ext); err == nil && !info.IsDir() {
+			return name + v.ext, v.encoding
+		}
+	}
+	return name, ""
+}
+
+// etag returns the ETag of a file, from the hash of its content when known, and of its encoding
+func (s *Server) etag(name, encoding string, info fs.FileInfo) string {
+	if encoding != "" {
+		encoding = "-" + encoding
+	}
+
+	if !s.Debug {
+		if a, ok := s.lookup(name); ok {
+			return `"` + a.hash[:2*hashLength] + encoding + `"`
+		}
+	}
+
+	// files added since the assets were loaded, and every file in debug mode, may change
+	return `W/"` + strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36) + encoding + `"`
+}
+
+// accepts reports whether an Accept-Encoding header accepts an encoding
+func accepts(acceptEncoding, encoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		if strings.TrimSpace(params[0]) != encoding {
+			continue
+		}
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if q, err := strconv.ParseFloat(value, 64); key == "q" && err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// fingerprint inserts the hash of a file before its extension, e.g. css/app.4f9a2c1e6b.css
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash[:hashLength] + ext
+}
+
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func readManifest(fsys fs.FS) (map[string]string, error) {
+	content, err := fs.ReadFile(fsys, ManifestFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest map[string]string
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// open returns the content of a file as an io.ReadSeeker, as http.ServeContent needs to serve ranges
+func open(fsys fs.FS, name string) (io.ReadSeekCloser, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if rs, ok := f.(io.ReadSeekCloser); ok {
+		return rs, nil
+	}
+
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{bytes.NewReader(content)}, nil
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
+// hidden reports whether a path has a file or folder starting with a dot, such as .env or .git
+func hidden(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isVariant reports whether a file is the pre-compressed variant of another, e.g. app.css.gz
+func isVariant(fsys fs.FS, name string) bool {
+	ext := path.Ext(name)
+	if ext != ".br" && ext != ".gz" {
+		return false
+	}
+	_, err := fs.Stat(fsys, strings.TrimSuffix(name, ext))
+	return err == nil
+}
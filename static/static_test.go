@@ -0,0 +1,132 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+var testFS = fstest.MapFS{
+	"css/app.css":           {Data: []byte("body { color: red; }")},
+	"css/app.css.br":        {Data: []byte("brotli")},
+	"css/app.css.gz":        {Data: []byte("gzip")},
+	"js/app.4f9a2c1e.js":    {Data: []byte("console.log('built')")},
+	"manifest.json":         {Data: []byte(`{"js/app.js": "js/app.4f9a2c1e.js"}`)},
+	"files/archive.tar.gz":  {Data: []byte("archive")},
+	".env":                  {Data: []byte("KEY=secret")},
+	"images/.hidden/me.png": {Data: []byte("png")},
+}
+
+func TestServer_Path(t *testing.T) {
+	s := New(testFS)
+
+	if path := s.Path("css/app.css"); !regexp.MustCompile(`^/public/css/app\.[0-9a-f]{10}\.css$`).MatchString(path) {
+		t.Errorf("expected a fingerprinted path, got %s", path)
+	}
+	if path := s.Path("/js/app.js"); path != "/public/js/app.4f9a2c1e.js" {
+		t.Errorf("expected the path of the manifest, got %s", path)
+	}
+	if path := s.Path("files/archive.tar.gz"); !regexp.MustCompile(`^/public/files/archive\.tar\.[0-9a-f]{10}\.gz$`).MatchString(path) {
+		t.Errorf("expected a fingerprinted path for a gzip file without its original, got %s", path)
+	}
+	if path := s.Path("missing.css"); path != "/public/missing.css" {
+		t.Errorf("expected an unknown file to keep its path, got %s", path)
+	}
+
+	s.Debug = true
+	if path := s.Path("css/app.css"); path != "/public/css/app.css" {
+		t.Errorf("expected no fingerprint in debug mode, got %s", path)
+	}
+}
+
+func TestServer_ServeHTTP(t *testing.T) {
+	s := New(testFS)
+	fingerprinted := s.Path("css/app.css")
+
+	var tests = []struct {
+		name            string
+		path            string
+		headers         map[string]string
+		status          int
+		body            string
+		cacheControl    string
+		contentEncoding string
+	}{
+		{"fingerprinted", fingerprinted, nil, http.StatusOK, "body { color: red; }", immutable, ""},
+		{"plain", "/public/css/app.css", nil, http.StatusOK, "body { color: red; }", revalidate, ""},
+		{"brotli", fingerprinted, map[string]string{"Accept-Encoding": "gzip, br"}, http.StatusOK, "brotli", immutable, "br"},
+		{"gzip", fingerprinted, map[string]string{"Accept-Encoding": "gzip, br;q=0"}, http.StatusOK, "gzip", immutable, "gzip"},
+		{"range", fingerprinted, map[string]string{"Accept-Encoding": "br", "Range": "bytes=0-3"}, http.StatusPartialContent, "body", immutable, ""},
+		{"built", "/public/js/app.4f9a2c1e.js", nil, http.StatusOK, "console.log('built')", immutable, ""},
+		{"missing", "/public/missing.css", nil, http.StatusNotFound, "", "", ""},
+		{"dotfile", "/public/.env", nil, http.StatusNotFound, "", "", ""},
+		{"hidden folder", "/public/images/.hidden/me.png", nil, http.StatusNotFound, "", "", ""},
+		{"manifest", "/public/manifest.json", nil, http.StatusNotFound, "", "", ""},
+		{"directory", "/public/css", nil, http.StatusNotFound, "", "", ""},
+		{"traversal", "/public/../static.go", nil, http.StatusNotFound, "", "", ""},
+	}
+
+	for _, e := range tests {
+		r := httptest.NewRequest("GET", e.path, nil)
+		for key, value := range e.headers {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, r)
+
+		if w.Code != e.status {
+			t.Errorf("%s: expected status %d, got %d", e.name, e.status, w.Code)
+			continue
+		}
+		if e.status == http.StatusNotFound {
+			continue
+		}
+		if w.Body.String() != e.body {
+			t.Errorf("%s: expected body %q, got %q", e.name, e.body, w.Body.String())
+		}
+		if got := w.Header().Get("Cache-Control"); got != e.cacheControl {
+			t.Errorf("%s: expected Cache-Control %q, got %q", e.name, e.cacheControl, got)
+		}
+		if got := w.Header().Get("Content-Encoding"); got != e.contentEncoding {
+			t.Errorf("%s: expected Content-Encoding %q, got %q", e.name, e.contentEncoding, got)
+		}
+		if got := w.Header().Get("Content-Type"); e.name != "built" && got != "text/css; charset=utf-8" {
+			t.Errorf("%s: expected the content type of the original file, got %q", e.name, got)
+		}
+	}
+}
+
+func TestServer_ETag(t *testing.T) {
+	s := New(testFS)
+
+	r := httptest.NewRequest("GET", "/public/css/app.css", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	r = httptest.NewRequest("GET", "/public/css/app.css", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 Not Modified for a matching ETag, got %d", w.Code)
+	}
+
+	r = httptest.NewRequest("GET", "/public/css/app.css", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the gzip variant to have its own ETag, got %d", w.Code)
+	}
+}
@@ -2,26 +2,36 @@ package medego
 
 import (
 	"database/sql"
+	"io/fs"
 	"log"
 	"net/url"
 	"time"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/PrinMeshia/medego/cache"
+	"github.com/PrinMeshia/medego/events"
+	"github.com/PrinMeshia/medego/i18n"
 	"github.com/PrinMeshia/medego/mailer"
+	"github.com/PrinMeshia/medego/pubsub"
 	"github.com/PrinMeshia/medego/render"
+	"github.com/PrinMeshia/medego/sse"
+	"github.com/PrinMeshia/medego/static"
+	"github.com/PrinMeshia/medego/ws"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-chi/chi/v5"
 	"github.com/robfig/cron/v3"
 )
 
 type Medego struct {
-	AppName       string
-	Debug         bool
-	Version       string
-	ErrorLog      *log.Logger
-	InfoLog       *log.Logger
-	RootPath      string
+	AppName  string
+	Debug    bool
+	Version  string
+	ErrorLog *log.Logger
+	InfoLog  *log.Logger
+	RootPath string
+	// FS holds the folders of the application, templates, mail, migrations, seeds, lang and public,
+	// e.g. an embed.FS to ship a single binary; they are read from RootPath when nil or in debug mode
+	FS            fs.FS
 	Routes        *chi.Mux
 	Render        *render.Render
 	Session       *scs.SessionManager
@@ -31,8 +41,17 @@ type Medego struct {
 	EncryptionKey string
 	Cache         cache.Cache
 	Scheduler     *cron.Cron
-	Mail          mailer.Mail
+	// Mail sends the messages through the queue of Mailer; tests may replace it with a mailertest.Fake
+	Mail          mailer.Sender
+	Mailer        *mailer.Mail
 	Server        Server
+	Events        *events.Bus
+	I18n          *i18n.I18n
+	Assets        *static.Server
+	PubSub        pubsub.Hub
+	SSE           *sse.Broker
+	WebSocket     *ws.Hub
+	ErrorReporter ErrorReporter
 }
 type Server struct {
 	ServerName string
@@ -76,8 +95,10 @@ type Database struct {
 }
 
 type Validation struct {
-	Data   url.Values
-	Errors map[string]string
+	Data       url.Values
+	Errors     map[string]string
+	Locale     string
+	translator i18n.Translator
 }
 
 type Encryption struct {
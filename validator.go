@@ -7,24 +7,58 @@ import (
 	"strings"
 	"time"
 
+	"github.com/PrinMeshia/medego/i18n"
 	"github.com/asaskevich/govalidator"
 )
 
 func (c *Medego) Validator(data url.Values) *Validation {
-	return &Validation{
+	v := &Validation{
 		Errors: make(map[string]string),
 		Data:   data,
 	}
+	if c.I18n != nil {
+		v.Locale = c.I18n.DefaultLocale
+		v.translator = c.I18n
+	}
+	return v
+}
+
+// ValidatorFor returns a validator of the form of a request, whose messages are in the locale of the request
+func (c *Medego) ValidatorFor(r *http.Request) *Validation {
+	v := c.Validator(r.Form)
+	if locale := i18n.Locale(r.Context()); locale != "" {
+		v.Locale = locale
+	}
+	return v
 }
 
 func (v *Validation) Valid() bool {
 	return len(v.Errors) == 0
 }
 
+// AddError adds the error of a field, unless it already has one. The message may be a key of
+// the message catalogs, translated in the locale of the validation.
 func (v *Validation) AddError(key, message string) {
 	if _, exists := v.Errors[key]; !exists {
-		v.Errors[key] = message
+		v.Errors[key] = v.translate(message, message)
+	}
+}
+
+// addError adds a built-in error, translated with the key of the message, or else in English
+func (v *Validation) addError(field, key, fallback string) {
+	if _, exists := v.Errors[field]; !exists {
+		v.Errors[field] = v.translate(key, fallback, "field", field)
+	}
+}
+
+func (v *Validation) translate(key, fallback string, args ...interface{}) string {
+	if v.translator == nil {
+		return fallback
+	}
+	if message := v.translator.T(v.Locale, key, args...); message != key {
+		return message
 	}
+	return fallback
 }
 
 func (v *Validation) Has(field string, r *http.Request) bool {
@@ -35,7 +69,7 @@ func (v *Validation) Required(r *http.Request, fields ...string) {
 	for _, field := range fields {
 		value := r.Form.Get(field)
 		if strings.TrimSpace(value) == "" {
-			v.AddError(field, "this field cannot be blank")
+			v.addError(field, "validation.required", "this field cannot be blank")
 		}
 	}
 }
@@ -47,33 +81,33 @@ func (v *Validation) Check(ok bool, key, message string) {
 
 func (v *Validation) IsEmail(field, value string) {
 	if !govalidator.IsEmail(value) {
-		v.AddError(field, "Invalid email address")
+		v.addError(field, "validation.email", "Invalid email address")
 	}
 }
 
 func (v *Validation) IsInt(field, value string) {
 	_, err := strconv.Atoi(value)
 	if err != nil {
-		v.AddError(field, "this field must be an integer")
+		v.addError(field, "validation.int", "this field must be an integer")
 	}
 }
 
 func (v *Validation) IsFloat(field, value string) {
 	_, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		v.AddError(field, "this field must be an float")
+		v.addError(field, "validation.float", "this field must be an float")
 	}
 }
 
 func (v *Validation) IsDateISO(field, value string) {
 	_, err := time.Parse("2006-01-02", value)
 	if err != nil {
-		v.AddError(field, "this field must be a date in the form of YYYY-MM-DD")
+		v.addError(field, "validation.date", "this field must be a date in the form of YYYY-MM-DD")
 	}
 }
 
 func (v *Validation) NoSpace(field, value string) {
 	if govalidator.HasWhitespace(value) {
-		v.AddError(field, "Spaces are not permitted")
+		v.addError(field, "validation.no_space", "Spaces are not permitted")
 	}
 }
@@ -0,0 +1,75 @@
+package medego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/PrinMeshia/medego/i18n"
+)
+
+// validationChecks runs each built-in check on an invalid value of its own field
+func validationChecks(v *Validation, r *http.Request) {
+	v.Required(r, "name")
+	v.IsEmail("email", "not an email")
+	v.IsInt("age", "x")
+	v.IsFloat("price", "x")
+	v.IsDateISO("birthday", "01/02/2006")
+	v.NoSpace("username", "a b")
+}
+
+func TestValidation_Messages(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Form = url.Values{}
+
+	// without catalog, the messages are in English
+	v := (&Medego{}).Validator(r.Form)
+	validationChecks(v, r)
+	if v.Valid() || v.Errors["name"] != "this field cannot be blank" || v.Errors["username"] != "Spaces are not permitted" {
+		t.Errorf("expected the default messages, got %v", v.Errors)
+	}
+
+	// the catalog scaffolded by medego new translates every check
+	translations := i18n.New("en")
+	if err := translations.LoadDir("./cmd/cli/templates/lang"); err != nil {
+		t.Fatal(err)
+	}
+	translations.Add("fr", map[string]string{"validation.required": "{field} est obligatoire", "terms.accepted": "les conditions doivent être acceptées"})
+	c := &Medego{I18n: translations}
+
+	v = c.Validator(r.Form)
+	validationChecks(v, r)
+
+	var tests = []struct {
+		field    string
+		expected string
+	}{
+		{"name", "name cannot be blank"},
+		{"email", "email must be a valid email address"},
+		{"age", "age must be an integer"},
+		{"price", "price must be a number"},
+		{"birthday", "birthday must be a date in the form of YYYY-MM-DD"},
+		{"username", "username cannot contain spaces"},
+	}
+
+	for _, e := range tests {
+		if v.Errors[e.field] != e.expected {
+			t.Errorf("%s: expected %q, got %q", e.field, e.expected, v.Errors[e.field])
+		}
+	}
+
+	// in the locale of the request, falling back to the default locale
+	v = c.ValidatorFor(r.WithContext(i18n.WithLocale(r.Context(), "fr")))
+	validationChecks(v, r)
+	if v.Errors["name"] != "name est obligatoire" || v.Errors["age"] != "age must be an integer" {
+		t.Errorf("expected the messages in French, got %v", v.Errors)
+	}
+
+	// custom errors are translated when they are keys of the catalog
+	v.AddError("terms", "terms.accepted")
+	v.AddError("other", "must be accepted")
+	if v.Errors["terms"] != "les conditions doivent être acceptées" || v.Errors["other"] != "must be accepted" {
+		t.Errorf("expected the custom errors, got %v", v.Errors)
+	}
+}
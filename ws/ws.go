@@ -0,0 +1,443 @@
+package ws
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PrinMeshia/medego/pubsub"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultSendBuffer     = 32
+	defaultPingInterval   = 30 * time.Second
+	defaultWriteWait      = 10 * time.Second
+	defaultMaxMessageSize = 64 << 10
+	topicPrefix           = "ws."
+)
+
+var (
+	// ErrClosed is returned when sending to a closed connection, or broadcasting on a closed hub
+	ErrClosed = errors.New("ws: connection closed")
+	// ErrSlowConsumer is returned when the send buffer of a connection is full; the connection is closed
+	ErrSlowConsumer = errors.New("ws: send buffer full")
+)
+
+// Hub upgrades requests to WebSocket connections, and broadcasts messages to the connections of rooms.
+// Broadcasts go through the pub/sub hub, so that they reach the connections of every instance, e.g.
+//
+//	app.WebSocket.OnConnect = func(c *ws.Conn) { _ = c.Join("users." + strconv.Itoa(c.UserID)) }
+//	mux.Get("/ws", app.WebSocket.ServeHTTP)
+//	...
+//	_ = app.WebSocket.BroadcastJSON("users.42", notification)
+type Hub struct {
+	PubSub   pubsub.Hub
+	Upgrader websocket.Upgrader
+
+	// Session returns the ID of the user logged in the session of a request, or 0
+	Session func(r *http.Request) int
+	// Token returns the ID of the user of a bearer token, given in the Authorization header, or in
+	// the access_token query parameter as browsers cannot set headers on WebSocket requests
+	Token func(token string) (int, error)
+	// AllowAnonymous accepts connections without user; otherwise they get 401 Unauthorized
+	AllowAnonymous bool
+
+	// OnConnect is called when a connection opens, e.g. to join rooms
+	OnConnect func(c *Conn)
+	// OnMessage is called with each message the client sends
+	OnMessage func(c *Conn, data []byte)
+	// OnClose is called once a connection has closed, and left its rooms
+	OnClose func(c *Conn)
+
+	// SendBuffer is the number of messages a connection may lag behind before it is closed, 32 by default
+	SendBuffer int
+	// PingInterval is the interval of the pings keeping connections alive, 30 seconds by default;
+	// connections not answering within twice the interval are closed
+	PingInterval time.Duration
+	// MaxMessageSize is the largest message accepted from clients, 64 KiB by default
+	MaxMessageSize int64
+
+	mu     sync.Mutex
+	conns  map[*Conn]struct{}
+	rooms  map[string]*room
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type room struct {
+	conns map[*Conn]struct{}
+	sub   *pubsub.Subscription
+}
+
+// NewHub returns a hub broadcasting through ps
+func NewHub(ps pubsub.Hub) *Hub {
+	return &Hub{
+		PubSub: ps,
+		conns:  make(map[*Conn]struct{}),
+		rooms:  make(map[string]*room),
+	}
+}
+
+// hijacker hijacks the connection of a response writer wrapped by a middleware, such as the
+// session one, which the upgrader could not hijack as it asserts http.Hijacker on the writer
+type hijacker struct {
+	http.ResponseWriter
+}
+
+func (w hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// ServeHTTP authenticates the request, upgrades it to a WebSocket connection and serves the
+// connection until it closes
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil || (userID == 0 && !h.AllowAnonymous) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	socket, err := h.Upgrader.Upgrade(hijacker{w}, r, nil)
+	if err != nil {
+		// the upgrader has answered with the error
+		return
+	}
+
+	c := &Conn{
+		UserID:  userID,
+		Request: r,
+		hub:     h,
+		socket:  socket,
+		send:    make(chan []byte, h.sendBuffer()),
+		rooms:   make(map[string]struct{}),
+		done:    make(chan struct{}),
+	}
+	if !h.register(c) {
+		_ = socket.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown"), time.Now().Add(defaultWriteWait))
+		_ = socket.Close()
+		return
+	}
+	defer h.unregister(c)
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		c.writeLoop()
+	}()
+
+	if h.OnConnect != nil {
+		h.OnConnect(c)
+	}
+
+	c.readLoop()
+	c.closeWith(websocket.CloseNormalClosure, "")
+	<-written
+}
+
+// Broadcast sends a message to the connections of a room, on every instance
+func (h *Hub) Broadcast(room string, data []byte) error {
+	h.mu.Lock()
+	closed := h.closed
+	h.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	return h.PubSub.Publish(topicPrefix+room, data)
+}
+
+// BroadcastJSON sends a value encoded as JSON to the connections of a room, on every instance
+func (h *Hub) BroadcastJSON(room string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return h.Broadcast(room, data)
+}
+
+// Connections returns the number of open connections of this instance
+func (h *Hub) Connections() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.conns)
+}
+
+// Shutdown closes every connection with 1001 Going Away, refuses new ones, and waits for the
+// connections to end or the context to be done
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	for c := range h.conns {
+		c.closeWith(websocket.CloseGoingAway, "server shutdown")
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// authenticate returns the user of the session, or else of the bearer token, or 0
+func (h *Hub) authenticate(r *http.Request) (int, error) {
+	if h.Session != nil {
+		if userID := h.Session(r); userID != 0 {
+			return userID, nil
+		}
+	}
+
+	if h.Token == nil {
+		return 0, nil
+	}
+
+	token := r.URL.Query().Get("access_token")
+	if scheme, value, found := strings.Cut(r.Header.Get("Authorization"), " "); found && strings.EqualFold(scheme, "Bearer") {
+		token = value
+	}
+	if token == "" {
+		return 0, nil
+	}
+	return h.Token(token)
+}
+
+func (h *Hub) register(c *Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+	if h.conns == nil {
+		h.conns = make(map[*Conn]struct{})
+	}
+	h.conns[c] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+func (h *Hub) unregister(c *Conn) {
+	h.mu.Lock()
+	for name := range c.rooms {
+		h.leave(c, name)
+	}
+	delete(h.conns, c)
+	h.mu.Unlock()
+
+	if h.OnClose != nil {
+		h.OnClose(c)
+	}
+	h.wg.Done()
+}
+
+// join adds a connection to a room, subscribing the room on the pub/sub hub with its first connection
+func (h *Hub) join(c *Conn, name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.rooms == nil {
+		h.rooms = make(map[string]*room)
+	}
+
+	rm, ok := h.rooms[name]
+	if !ok {
+		sub, err := h.PubSub.Subscribe(topicPrefix + name)
+		if err != nil {
+			return err
+		}
+		rm = &room{conns: make(map[*Conn]struct{}), sub: sub}
+		h.rooms[name] = rm
+		go h.relay(rm)
+	}
+
+	rm.conns[c] = struct{}{}
+	c.rooms[name] = struct{}{}
+	return nil
+}
+
+// leave removes a connection from a room, unsubscribing the room with its last connection
+func (h *Hub) leave(c *Conn, name string) {
+	delete(c.rooms, name)
+
+	rm, ok := h.rooms[name]
+	if !ok {
+		return
+	}
+	delete(rm.conns, c)
+	if len(rm.conns) == 0 {
+		delete(h.rooms, name)
+		rm.sub.Close()
+	}
+}
+
+// relay sends the messages of a room to its connections
+func (h *Hub) relay(rm *room) {
+	for msg := range rm.sub.C {
+		h.mu.Lock()
+		conns := make([]*Conn, 0, len(rm.conns))
+		for c := range rm.conns {
+			conns = append(conns, c)
+		}
+		h.mu.Unlock()
+
+		for _, c := range conns {
+			_ = c.Send(msg.Data)
+		}
+	}
+}
+
+func (h *Hub) sendBuffer() int {
+	if h.SendBuffer <= 0 {
+		return defaultSendBuffer
+	}
+	return h.SendBuffer
+}
+
+func (h *Hub) pingInterval() time.Duration {
+	if h.PingInterval <= 0 {
+		return defaultPingInterval
+	}
+	return h.PingInterval
+}
+
+func (h *Hub) maxMessageSize() int64 {
+	if h.MaxMessageSize <= 0 {
+		return defaultMaxMessageSize
+	}
+	return h.MaxMessageSize
+}
+
+// Conn is a WebSocket connection of a user, or of an anonymous client when UserID is 0
+type Conn struct {
+	UserID int
+	// Request is the upgraded request
+	Request *http.Request
+
+	hub    *Hub
+	socket *websocket.Conn
+	send   chan []byte
+	// rooms is guarded by the mutex of the hub
+	rooms map[string]struct{}
+
+	closeOnce sync.Once
+	done      chan struct{}
+	closeCode int
+	closeText string
+}
+
+// Send queues a message for the client. When the client lags behind by more than the send buffer,
+// the connection is closed and ErrSlowConsumer returned.
+func (c *Conn) Send(data []byte) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return nil
+	default:
+		c.closeWith(websocket.CloseTryAgainLater, "send buffer full")
+		return ErrSlowConsumer
+	}
+}
+
+// SendJSON queues a value encoded as JSON for the client
+func (c *Conn) SendJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(data)
+}
+
+// Join adds the connection to a room, to receive its broadcasts
+func (c *Conn) Join(room string) error {
+	return c.hub.join(c, room)
+}
+
+// Leave removes the connection from a room
+func (c *Conn) Leave(room string) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.hub.leave(c, room)
+}
+
+// Close closes the connection normally
+func (c *Conn) Close() {
+	c.closeWith(websocket.CloseNormalClosure, "")
+}
+
+func (c *Conn) closeWith(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode, c.closeText = code, text
+		close(c.done)
+	})
+}
+
+// readLoop reads the messages of the client until the connection fails or closes; pongs, or any
+// message, extend the read deadline
+func (c *Conn) readLoop() {
+	wait := 2 * c.hub.pingInterval()
+	c.socket.SetReadLimit(c.hub.maxMessageSize())
+	_ = c.socket.SetReadDeadline(time.Now().Add(wait))
+	c.socket.SetPongHandler(func(string) error {
+		return c.socket.SetReadDeadline(time.Now().Add(wait))
+	})
+
+	for {
+		_, data, err := c.socket.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = c.socket.SetReadDeadline(time.Now().Add(wait))
+
+		if c.hub.OnMessage != nil {
+			c.hub.OnMessage(c, data)
+		}
+	}
+}
+
+// writeLoop writes the queued messages and the pings, the only writer of the connection, and
+// closes it once done
+func (c *Conn) writeLoop() {
+	ping := time.NewTicker(c.hub.pingInterval())
+	defer func() {
+		ping.Stop()
+		_ = c.socket.Close()
+	}()
+
+	for {
+		select {
+		case data := <-c.send:
+			_ = c.socket.SetWriteDeadline(time.Now().Add(defaultWriteWait))
+			if err := c.socket.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.closeWith(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ping.C:
+			if err := c.socket.WriteControl(websocket.PingMessage, nil, time.Now().Add(defaultWriteWait)); err != nil {
+				c.closeWith(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			message := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+			_ = c.socket.WriteControl(websocket.CloseMessage, message, time.Now().Add(defaultWriteWait))
+			return
+		}
+	}
+}
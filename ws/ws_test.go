@@ -0,0 +1,225 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PrinMeshia/medego/pubsub"
+	"github.com/gorilla/websocket"
+)
+
+func newTestHub() *Hub {
+	hub := NewHub(pubsub.NewMemory())
+	hub.Session = func(r *http.Request) int {
+		id, _ := strconv.Atoi(r.Header.Get("X-User"))
+		return id
+	}
+	hub.Token = func(token string) (int, error) {
+		if token != "valid-token" {
+			return 0, errors.New("invalid token")
+		}
+		return 7, nil
+	}
+	return hub
+}
+
+func dial(t *testing.T, server *httptest.Server, path string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	return websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+path, header)
+}
+
+func read(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// waitFor waits until the hub has the number of connections
+func waitFor(hub *Hub, count int) {
+	for i := 0; i < 200 && hub.Connections() != count; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHub_Authenticate(t *testing.T) {
+	hub := newTestHub()
+	users := make(chan int, 3)
+	hub.OnConnect = func(c *Conn) { users <- c.UserID }
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	var tests = []struct {
+		name   string
+		path   string
+		header http.Header
+		userID int
+	}{
+		{"session", "/", http.Header{"X-User": {"3"}}, 3},
+		{"bearer token", "/", http.Header{"Authorization": {"Bearer valid-token"}}, 7},
+		{"query token", "/?access_token=valid-token", nil, 7},
+		{"invalid token", "/", http.Header{"Authorization": {"Bearer forged"}}, 0},
+		{"anonymous", "/", nil, 0},
+	}
+
+	for _, e := range tests {
+		conn, resp, err := dial(t, server, e.path, e.header)
+		if e.userID == 0 {
+			if err == nil || resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("%s: expected 401 Unauthorized", e.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", e.name, err)
+			continue
+		}
+
+		if userID := <-users; userID != e.userID {
+			t.Errorf("%s: expected user %d, got %d", e.name, e.userID, userID)
+		}
+		conn.Close()
+	}
+
+	hub.AllowAnonymous = true
+	conn, _, err := dial(t, server, "/", nil)
+	if err != nil {
+		t.Fatalf("expected anonymous connections to be allowed: %s", err)
+	}
+	conn.Close()
+}
+
+func TestHub_Rooms(t *testing.T) {
+	hub := newTestHub()
+	joined := make(chan struct{}, 2)
+	hub.OnConnect = func(c *Conn) {
+		_ = c.Join("users." + strconv.Itoa(c.UserID))
+		_ = c.Join("everyone")
+		joined <- struct{}{}
+	}
+	hub.OnMessage = func(c *Conn, data []byte) {
+		_ = c.Send(append([]byte("echo "), data...))
+	}
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	first, _, err := dial(t, server, "/", http.Header{"X-User": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, _, err := dial(t, server, "/", http.Header{"X-User": {"2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	<-joined
+	<-joined
+
+	if err := hub.Broadcast("users.2", []byte("for 2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := hub.BroadcastJSON("everyone", map[string]string{"hello": "all"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := read(t, first); got != `{"hello":"all"}` {
+		t.Errorf("expected the first user to only get the broadcast to everyone, got %s", got)
+	}
+	// the messages of different rooms may arrive in any order
+	received := map[string]bool{read(t, second): true, read(t, second): true}
+	if !received["for 2"] || !received[`{"hello":"all"}`] {
+		t.Errorf("expected the second user to get its message and the broadcast to everyone, got %v", received)
+	}
+
+	if err := first.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, first); got != "echo ping" {
+		t.Errorf("expected the echo of the message, got %s", got)
+	}
+
+	second.Close()
+	waitFor(hub, 1)
+	hub.mu.Lock()
+	_, kept := hub.rooms["users.2"]
+	hub.mu.Unlock()
+	if kept {
+		t.Error("expected the room to be removed with its last connection")
+	}
+}
+
+func TestConn_SlowConsumer(t *testing.T) {
+	hub := newTestHub()
+	hub.SendBuffer = 1
+	sent := make(chan error, 1)
+	hub.OnConnect = func(c *Conn) {
+		// the writer may take the first message before the others are queued
+		var err error
+		for i := 0; i < 100 && err == nil; i++ {
+			err = c.Send([]byte("message"))
+		}
+		sent <- err
+	}
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	conn, _, err := dial(t, server, "/", http.Header{"X-User": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := <-sent; err != ErrSlowConsumer {
+		t.Errorf("expected ErrSlowConsumer, got %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err = conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+		t.Errorf("expected the connection to be closed with 1013, got %v", err)
+	}
+}
+
+func TestHub_Shutdown(t *testing.T) {
+	hub := newTestHub()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	conn, _, err := dial(t, server, "/", http.Header{"X-User": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitFor(hub, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected the connection to be closed with 1001, got %v", err)
+	}
+	if hub.Connections() != 0 {
+		t.Errorf("expected no connection left, got %d", hub.Connections())
+	}
+	if err := hub.Broadcast("everyone", nil); err != ErrClosed {
+		t.Errorf("expected ErrClosed broadcasting after shutdown, got %v", err)
+	}
+}